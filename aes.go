@@ -3,39 +3,43 @@ package toyaes
 import (
 	ccipher "crypto/cipher"
 	"encoding/binary"
-	"fmt"
 )
 
+//go:generate go run ./cmd/gentables -o constant.go
+
 // Kb: word number (paintext)
 // Nk: Word number (key)
 // Nr: round number
 // 1word= 4byte = 32bit
 // input is 4*4 byte
 
-func subBytes(state []byte) {
+const nb = 4
+
+func subBytes(state *[16]byte) {
 	for i := 0; i < len(state); i++ {
 		state[i] = sbox[state[i]]
 	}
 }
 
-func invSubBytes(state []byte) {
+func invSubBytes(state *[16]byte) {
 	for i := 0; i < len(state); i++ {
 		state[i] = isbox[state[i]]
 	}
 }
 
-func shiftRows(state []byte) {
+func shiftRows(state *[16]byte) {
 	state[1], state[5], state[9], state[13] = state[5], state[9], state[13], state[1]
 	state[2], state[6], state[10], state[14] = state[10], state[14], state[2], state[6]
 	state[3], state[7], state[11], state[15] = state[15], state[3], state[7], state[11]
 }
 
-func invShiftRows(state []byte) {
+func invShiftRows(state *[16]byte) {
 	state[1], state[5], state[9], state[13] = state[13], state[1], state[5], state[9]
 	state[2], state[6], state[10], state[14] = state[10], state[14], state[2], state[6]
 	state[3], state[7], state[11], state[15] = state[7], state[11], state[15], state[3]
 }
 
+// Mul returns x * y in the field of AES
 func Mul(x, y byte) byte {
 	return mul(x, y)
 }
@@ -55,25 +59,12 @@ func mul(x, y byte) byte {
 	case 0x09:
 		return mul0x09[y]
 	default:
-		panic(fmt.Sprintf("invalid input: %X", x))
-	}
-	// sum := byte(0)
-	// for i := 0; i < 8; i++ {
-	// 	if y&1 == 1 {
-	// 		sum ^= x // add
-	// 	}
-	// 	msb := x & 0x80
-	// 	x <<= 1
-	// 	if msb == 0x80 {
-	// 		x ^= 0x1b // add
-	// 	}
-	// 	y >>= 1
-	// }
-	// return sum
-}
-
-func mixColumns(state []byte) {
-	tmp := make([]byte, 16)
+		return AESField.Mul(x, y)
+	}
+}
+
+func mixColumns(state *[16]byte) {
+	var tmp [16]byte
 	// add is xor
 	for i := 0; i < 4; i++ {
 		tmp[i*4] = mul(0x02, state[i*4]) ^ mul(0x03, state[i*4+1]) ^ state[i*4+2] ^ state[i*4+3]
@@ -81,11 +72,11 @@ func mixColumns(state []byte) {
 		tmp[i*4+2] = state[i*4] ^ state[i*4+1] ^ mul(0x02, state[i*4+2]) ^ mul(0x03, state[i*4+3])
 		tmp[i*4+3] = mul(0x03, state[i*4]) ^ state[i*4+1] ^ state[i*4+2] ^ mul(0x02, state[i*4+3])
 	}
-	copy(state, tmp)
+	*state = tmp
 }
 
-func invMixColumns(state []byte) {
-	tmp := make([]byte, 16)
+func invMixColumns(state *[16]byte) {
+	var tmp [16]byte
 	// add is xor
 	for i := 0; i < 4; i++ {
 		tmp[i*4] = mul(0x0e, state[i*4]) ^ mul(0x0b, state[i*4+1]) ^ mul(0x0d, state[i*4+2]) ^ mul(0x09, state[i*4+3])
@@ -93,17 +84,16 @@ func invMixColumns(state []byte) {
 		tmp[i*4+2] = mul(0x0d, state[i*4]) ^ mul(0x09, state[i*4+1]) ^ mul(0x0e, state[i*4+2]) ^ mul(0x0b, state[i*4+3])
 		tmp[i*4+3] = mul(0x0b, state[i*4]) ^ mul(0x0d, state[i*4+1]) ^ mul(0x09, state[i*4+2]) ^ mul(0x0e, state[i*4+3])
 	}
-	copy(state, tmp)
+	*state = tmp
 }
 
-func addRoundKey(state []byte, word []uint32) {
-	addrktmp := make([]byte, 16)
-	binary.BigEndian.PutUint32(addrktmp[0:4], word[0])
-	binary.BigEndian.PutUint32(addrktmp[4:8], word[1])
-	binary.BigEndian.PutUint32(addrktmp[8:12], word[2])
-	binary.BigEndian.PutUint32(addrktmp[12:16], word[3])
-	for i := 0; i < len(state); i++ {
-		state[i] ^= addrktmp[i]
+func addRoundKey(state *[16]byte, word []uint32) {
+	for i := 0; i < nb; i++ {
+		w := word[i]
+		state[4*i] ^= byte(w >> 24)
+		state[4*i+1] ^= byte(w >> 16)
+		state[4*i+2] ^= byte(w >> 8)
+		state[4*i+3] ^= byte(w)
 	}
 }
 
@@ -121,22 +111,22 @@ func cipher(input, out []byte, word []uint32) {
 
 	nr := nr(word)
 
-	state := make([]byte, 16)
-	copy(state, input)
+	var state [16]byte
+	copy(state[:], input)
 
-	addRoundKey(state, word[0:nb])
+	addRoundKey(&state, word[0:nb])
 	for i := 1; i < nr; i++ {
-		subBytes(state)
-		shiftRows(state)
-		mixColumns(state)
-		addRoundKey(state, word[i*nb:(i+1)*nb]) // (i+1)*nb = i*nb + nb
+		subBytes(&state)
+		shiftRows(&state)
+		mixColumns(&state)
+		addRoundKey(&state, word[i*nb:(i+1)*nb]) // (i+1)*nb = i*nb + nb
 	}
-	subBytes(state)
-	shiftRows(state)
-	addRoundKey(state, word[nr*nb:(nr+1)*nb]) // (nr+1)*nb = nr*nb + nb
+	subBytes(&state)
+	shiftRows(&state)
+	addRoundKey(&state, word[nr*nb:(nr+1)*nb]) // (nr+1)*nb = nr*nb + nb
 
 	// result
-	copy(out, state)
+	copy(out, state[:])
 }
 
 func invCipher(input, out []byte, word []uint32) {
@@ -149,30 +139,80 @@ func invCipher(input, out []byte, word []uint32) {
 
 	nr := nr(word)
 
-	state := make([]byte, 16)
-	copy(state, input)
+	var state [16]byte
+	copy(state[:], input)
 
-	addRoundKey(state, word[nr*nb:(nr+1)*nb]) // (nr+1)*nb = nr*nb + nb
+	addRoundKey(&state, word[nr*nb:(nr+1)*nb]) // (nr+1)*nb = nr*nb + nb
 	for i := nr - 1; i > 0; i-- {
-		invShiftRows(state)
-		invSubBytes(state)
-		addRoundKey(state, word[i*nb:(i+1)*nb]) // (i+1)*nb = i*nb + nb
-		invMixColumns(state)
-	}
-	invShiftRows(state)
-	invSubBytes(state)
-	addRoundKey(state, word[0:nb])
+		invShiftRows(&state)
+		invSubBytes(&state)
+		addRoundKey(&state, word[i*nb:(i+1)*nb]) // (i+1)*nb = i*nb + nb
+		invMixColumns(&state)
+	}
+	invShiftRows(&state)
+	invSubBytes(&state)
+	addRoundKey(&state, word[0:nb])
+	// result
+	copy(out, state[:])
+}
+
+// eqInvCipher is the equivalent inverse cipher (FIPS-197 section 5.3.5).
+// It has the same structure as cipher, with the inverse of each step and
+// dword made by decKeyExpansion.
+func eqInvCipher(input, out []byte, dword []uint32) {
+	if len(input) != 4*nb {
+		panic("invalid length")
+	}
+	if len(out) != 4*nb {
+		panic("invalid length")
+	}
+
+	nr := nr(dword)
+
+	var state [16]byte
+	copy(state[:], input)
+
+	addRoundKey(&state, dword[0:nb])
+	for i := 1; i < nr; i++ {
+		invSubBytes(&state)
+		invShiftRows(&state)
+		invMixColumns(&state)
+		addRoundKey(&state, dword[i*nb:(i+1)*nb]) // (i+1)*nb = i*nb + nb
+	}
+	invSubBytes(&state)
+	invShiftRows(&state)
+	addRoundKey(&state, dword[nr*nb:(nr+1)*nb]) // (nr+1)*nb = nr*nb + nb
+
 	// result
-	copy(out, state)
+	copy(out, state[:])
+}
+
+// decKeyExpansion makes the key schedule of the equivalent inverse cipher.
+// The round keys are in the order of use, i.e. reversed from word, and
+// invMixColumns is applied to all of them but the first and the last.
+// Because invMixColumns is linear, it can be swapped with addRoundKey.
+func decKeyExpansion(word, dword []uint32) {
+	nr := nr(word)
+	var rk [16]byte
+	for i := 0; i <= nr; i++ {
+		copy(dword[i*nb:(i+1)*nb], word[(nr-i)*nb:(nr-i+1)*nb])
+		if i == 0 || i == nr {
+			continue
+		}
+		for j := 0; j < nb; j++ {
+			binary.BigEndian.PutUint32(rk[4*j:], dword[i*nb+j])
+		}
+		invMixColumns(&rk)
+		for j := 0; j < nb; j++ {
+			dword[i*nb+j] = binary.BigEndian.Uint32(rk[4*j:])
+		}
+	}
 }
 
 func rotWord(w uint32) uint32 { return w<<8 | w>>24 }
 
 func subWord(w uint32) uint32 {
-	sbwtmp := make([]byte, 4)
-	binary.BigEndian.PutUint32(sbwtmp, w)
-	subBytes(sbwtmp)
-	return binary.BigEndian.Uint32(sbwtmp)
+	return uint32(sbox[w>>24])<<24 | uint32(sbox[w>>16&0xff])<<16 | uint32(sbox[w>>8&0xff])<<8 | uint32(sbox[w&0xff])
 }
 
 func keyExpansion(key []byte, word []uint32) {
@@ -199,37 +239,90 @@ func keyExpansion(key []byte, word []uint32) {
 
 var _ ccipher.Block = (*toyAES)(nil)
 
+// maxWords is the number of words of the key schedule for a 256-bit key
+const maxWords = nb * (14 + 1)
+
 type toyAES struct {
-	word []uint32
+	// word and dword are fixed size so that the key schedule needs no allocation.
+	// Only the first n words are used.
+	word [maxWords]uint32
+	// dword is the key schedule of the equivalent inverse cipher, set if ttable or eqInv is used
+	dword  [maxWords]uint32
+	n      int
+	ttable bool
+	eqInv  bool
 }
 
 // BlockSize implements cipher.Block
 func (*toyAES) BlockSize() int { return 16 }
 
-func NewToyAES(key []byte) ccipher.Block {
-	nk := len(key) / 4 // 4,6,8
+// Option configures the implementation of NewToyAES
+type Option func(*toyAES)
+
+// WithTTables selects the implementation with 32-bit T-tables, which merges
+// subBytes, shiftRows and mixColumns into table lookups.
+// The default is the readable implementation following FIPS-197.
+func WithTTables() Option {
+	return func(c *toyAES) { c.ttable = true }
+}
+
+// WithEquivalentInverseCipher makes Decrypt use the equivalent inverse cipher
+// of FIPS-197 section 5.3.5 instead of the straightforward inverse.
+// WithTTables always decrypts by the equivalent inverse cipher.
+func WithEquivalentInverseCipher() Option {
+	return func(c *toyAES) { c.eqInv = true }
+}
+
+// NewToyAES returns AES for a 16, 24 or 32-byte key.
+// It panics for any other key length; NewToyAESWithError returns an error instead.
+func NewToyAES(key []byte, opts ...Option) ccipher.Block {
+	c, err := NewToyAESWithError(key, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewToyAESWithError is NewToyAES which returns KeySizeError for an invalid key length
+func NewToyAESWithError(key []byte, opts ...Option) (ccipher.Block, error) {
 	var nr int
-	switch nk {
-	case 4:
+	switch len(key) {
+	case 16:
 		nr = 10
-	case 6:
+	case 24:
 		nr = 12
-	case 8:
+	case 32:
 		nr = 14
 	default:
-		panic("invalid key length")
+		return nil, KeySizeError(len(key))
+	}
+	c := &toyAES{n: nb * (nr + 1)}
+	keyExpansion(key, c.word[:c.n])
+	for _, opt := range opts {
+		opt(c)
 	}
-	word := make([]uint32, nb*(nr+1))
-	keyExpansion(key, word)
-	return &toyAES{
-		word: word,
+	if c.ttable || c.eqInv {
+		decKeyExpansion(c.word[:c.n], c.dword[:c.n])
 	}
+	return c, nil
 }
 
 func (c *toyAES) Encrypt(dst, src []byte) {
-	cipher(src, dst, c.word)
+	if c.ttable {
+		cipherT(src, dst, c.word[:c.n])
+		return
+	}
+	cipher(src, dst, c.word[:c.n])
 }
 
 func (c *toyAES) Decrypt(dst, src []byte) {
-	invCipher(src, dst, c.word)
+	if c.ttable {
+		invCipherT(src, dst, c.dword[:c.n])
+		return
+	}
+	if c.eqInv {
+		eqInvCipher(src, dst, c.dword[:c.n])
+		return
+	}
+	invCipher(src, dst, c.word[:c.n])
 }
@@ -0,0 +1,429 @@
+package toyaes
+
+import (
+	ccipher "crypto/cipher"
+	"encoding/binary"
+)
+
+// Bitsliced implementation.
+//
+// The state of up to bsBlocks blocks is held in 8 uint64: bit j of every byte
+// is in q[j], and byte i of block b is at bit position b*16+i. Every step is
+// a fixed sequence of bitwise operations, so no memory access and no branch
+// depends on the key or the data.
+//
+// See: J. Boyar, R. Peralta, "A new combinational logic minimization
+// technique with applications to cryptology"
+
+// bsBlocks is the number of blocks processed in parallel
+const bsBlocks = 4
+
+// bitslice is the state in bit planes
+type bitslice [8]uint64
+
+func (q *bitslice) load(src []byte) {
+	*q = bitslice{}
+	for p, v := range src {
+		for j := 0; j < 8; j++ {
+			q[j] |= uint64(v>>j&1) << p
+		}
+	}
+}
+
+func (q *bitslice) store(dst []byte) {
+	for p := range dst {
+		var v byte
+		for j := 0; j < 8; j++ {
+			v |= byte(q[j]>>p&1) << j
+		}
+		dst[p] = v
+	}
+}
+
+func (q *bitslice) xor(k *bitslice) {
+	for j := range q {
+		q[j] ^= k[j]
+	}
+}
+
+// xorConst adds the byte c to every byte
+func (q *bitslice) xorConst(c byte) {
+	for j := range q {
+		if c>>j&1 == 1 {
+			q[j] = ^q[j]
+		}
+	}
+}
+
+// bsSbox is the S-box circuit of Boyar and Peralta
+func bsSbox(q *bitslice) {
+	x0, x1, x2, x3 := q[7], q[6], q[5], q[4]
+	x4, x5, x6, x7 := q[3], q[2], q[1], q[0]
+
+	// top linear transformation
+	y14 := x3 ^ x5
+	y13 := x0 ^ x6
+	y9 := x0 ^ x3
+	y8 := x0 ^ x5
+	t0 := x1 ^ x2
+	y1 := t0 ^ x7
+	y4 := y1 ^ x3
+	y12 := y13 ^ y14
+	y2 := y1 ^ x0
+	y5 := y1 ^ x6
+	y3 := y5 ^ y8
+	t1 := x4 ^ y12
+	y15 := t1 ^ x5
+	y20 := t1 ^ x1
+	y6 := y15 ^ x7
+	y10 := y15 ^ t0
+	y11 := y20 ^ y9
+	y7 := x7 ^ y11
+	y17 := y10 ^ y11
+	y19 := y10 ^ y8
+	y16 := t0 ^ y11
+	y21 := y13 ^ y16
+	y18 := x0 ^ y16
+
+	// non-linear section: inversion in GF(2^8)
+	t2 := y12 & y15
+	t3 := y3 & y6
+	t4 := t3 ^ t2
+	t5 := y4 & x7
+	t6 := t5 ^ t2
+	t7 := y13 & y16
+	t8 := y5 & y1
+	t9 := t8 ^ t7
+	t10 := y2 & y7
+	t11 := t10 ^ t7
+	t12 := y9 & y11
+	t13 := y14 & y17
+	t14 := t13 ^ t12
+	t15 := y8 & y10
+	t16 := t15 ^ t12
+	t17 := t4 ^ t14
+	t18 := t6 ^ t16
+	t19 := t9 ^ t14
+	t20 := t11 ^ t16
+	t21 := t17 ^ y20
+	t22 := t18 ^ y19
+	t23 := t19 ^ y21
+	t24 := t20 ^ y18
+
+	t25 := t21 ^ t22
+	t26 := t21 & t23
+	t27 := t24 ^ t26
+	t28 := t25 & t27
+	t29 := t28 ^ t22
+	t30 := t23 ^ t24
+	t31 := t22 ^ t26
+	t32 := t31 & t30
+	t33 := t32 ^ t24
+	t34 := t23 ^ t33
+	t35 := t27 ^ t33
+	t36 := t24 & t35
+	t37 := t36 ^ t34
+	t38 := t27 ^ t36
+	t39 := t29 & t38
+	t40 := t25 ^ t39
+
+	t41 := t40 ^ t37
+	t42 := t29 ^ t33
+	t43 := t29 ^ t40
+	t44 := t33 ^ t37
+	t45 := t42 ^ t41
+	z0 := t44 & y15
+	z1 := t37 & y6
+	z2 := t33 & x7
+	z3 := t43 & y16
+	z4 := t40 & y1
+	z5 := t29 & y7
+	z6 := t42 & y11
+	z7 := t45 & y17
+	z8 := t41 & y10
+	z9 := t44 & y12
+	z10 := t37 & y3
+	z11 := t33 & y4
+	z12 := t43 & y13
+	z13 := t40 & y5
+	z14 := t29 & y2
+	z15 := t42 & y9
+	z16 := t45 & y14
+	z17 := t41 & y8
+
+	// bottom linear transformation
+	t46 := z15 ^ z16
+	t47 := z10 ^ z11
+	t48 := z5 ^ z13
+	t49 := z9 ^ z10
+	t50 := z2 ^ z12
+	t51 := z2 ^ z5
+	t52 := z7 ^ z8
+	t53 := z0 ^ z3
+	t54 := z6 ^ z7
+	t55 := z16 ^ z17
+	t56 := z12 ^ t48
+	t57 := t50 ^ t53
+	t58 := z4 ^ t46
+	t59 := z3 ^ t54
+	t60 := t46 ^ t57
+	t61 := z14 ^ t57
+	t62 := t52 ^ t58
+	t63 := t49 ^ t58
+	t64 := z4 ^ t59
+	t65 := t61 ^ t62
+	t66 := z1 ^ t63
+	s0 := t59 ^ t63
+	s6 := t56 ^ ^t62
+	s7 := t48 ^ ^t60
+	t67 := t64 ^ t65
+	s3 := t53 ^ t66
+	s4 := t51 ^ t66
+	s5 := t47 ^ t65
+	s1 := t64 ^ ^s3
+	s2 := t55 ^ ^t67
+
+	q[7], q[6], q[5], q[4] = s0, s1, s2, s3
+	q[3], q[2], q[1], q[0] = s4, s5, s6, s7
+}
+
+// bsInvAffine is the linear part of the inverse of the affine
+// transformation: b'(i) = b(i+2) ^ b(i+5) ^ b(i+7)
+func bsInvAffine(q *bitslice) {
+	var r bitslice
+	for i := 0; i < 8; i++ {
+		r[i] = q[(i+2)%8] ^ q[(i+5)%8] ^ q[(i+7)%8]
+	}
+	*q = r
+}
+
+// bsInvSbox computes isbox with the S-box circuit.
+// With the linear part L of the affine transformation, sbox(x) = L(x^-1) ^ 0x63,
+// so isbox(x) = L^-1(sbox(L^-1(x ^ 0x63)) ^ 0x63).
+func bsInvSbox(q *bitslice) {
+	q.xorConst(0x63)
+	bsInvAffine(q)
+	bsSbox(q)
+	q.xorConst(0x63)
+	bsInvAffine(q)
+}
+
+// rep16 repeats a 16-bit lane mask for every block
+const rep16 = 0x0001000100010001
+
+// rotateColumns rotates each row by k bits in the 16-bit lane of the block.
+// Byte i = 4c+r is in column c, so k = 4 moves every byte one column left.
+func rotateColumns(x uint64, k uint) uint64 {
+	low := uint64(1)<<(16-k) - 1
+	return (x>>k)&(low*rep16) | (x<<(16-k))&(^low&0xffff*rep16)
+}
+
+// bsShiftRows moves row r left by r columns
+func bsShiftRows(q *bitslice, inverse bool) {
+	for j := range q {
+		x := q[j]
+		out := x & (0x1111 * rep16) // row 0
+		for r := uint(1); r < 4; r++ {
+			k := 4 * r
+			if inverse {
+				k = 16 - 4*r
+			}
+			out |= rotateColumns(x&(0x1111<<r*rep16), k)
+		}
+		q[j] = out
+	}
+}
+
+// rotateRows returns the state where row r of each column has row r+k
+func rotateRows(x uint64, k uint) uint64 {
+	low := uint64(1)<<(4-k) - 1
+	return (x>>k)&(low*0x1111111111111111) | (x<<(4-k))&((^low&0xf)*0x1111111111111111)
+}
+
+// bsXtime multiplies every byte by {02}
+func bsXtime(q *bitslice) {
+	hi := q[7]
+	q[7], q[6], q[5], q[4] = q[6], q[5], q[4], q[3]^hi
+	q[3], q[2], q[1], q[0] = q[2]^hi, q[1], q[0]^hi, hi
+}
+
+// bsMixColumns computes 2a(r) ^ 3a(r+1) ^ a(r+2) ^ a(r+3) for each row
+func bsMixColumns(q *bitslice) {
+	var a1, t bitslice
+	for j := range q {
+		a1[j] = rotateRows(q[j], 1)
+		t[j] = q[j] ^ a1[j]
+	}
+	bsXtime(&t)
+	for j := range q {
+		q[j] = t[j] ^ a1[j] ^ rotateRows(q[j], 2) ^ rotateRows(q[j], 3)
+	}
+}
+
+// bsInvMixColumns adds 4(a(r) ^ a(r+2)) to each row and then applies
+// bsMixColumns, because invMixColumns = mixColumns * (04 x^2 + 05).
+func bsInvMixColumns(q *bitslice) {
+	var u bitslice
+	for j := range q {
+		u[j] = q[j] ^ rotateRows(q[j], 2)
+	}
+	bsXtime(&u)
+	bsXtime(&u)
+	q.xor(&u)
+	bsMixColumns(q)
+}
+
+var _ ccipher.Block = (*BitslicedAES)(nil)
+
+// BitslicedAES is AES implemented without secret-dependent table lookups or
+// branches. EncryptBlocks and DecryptBlocks process 4 blocks at once in the
+// uint64 lanes; Encrypt and Decrypt of cipher.Block fill only one of them.
+type BitslicedAES struct {
+	// rk[i] is round key i broadcast to every block
+	rk [14 + 1]bitslice
+	nr int
+}
+
+// NewBitslicedAES returns BitslicedAES for a 16, 24 or 32-byte key.
+// It panics for any other key length; NewBitslicedAESWithError returns an error instead.
+func NewBitslicedAES(key []byte) *BitslicedAES {
+	c, err := NewBitslicedAESWithError(key)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewBitslicedAESWithError is NewBitslicedAES which returns KeySizeError for an invalid key length
+func NewBitslicedAESWithError(key []byte) (*BitslicedAES, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, KeySizeError(len(key))
+	}
+	c := &BitslicedAES{nr: len(key)/4 + 6}
+	var words [maxWords]uint32
+	word := words[:nb*(c.nr+1)]
+	bsKeyExpansion(key, word)
+
+	var b [16 * bsBlocks]byte
+	for i := 0; i <= c.nr; i++ {
+		for j := 0; j < nb; j++ {
+			binary.BigEndian.PutUint32(b[4*j:], word[i*nb+j])
+		}
+		for k := 1; k < bsBlocks; k++ {
+			copy(b[16*k:], b[:16])
+		}
+		c.rk[i].load(b[:])
+	}
+	return c, nil
+}
+
+// bsKeyExpansion is keyExpansion with subWord by the S-box circuit
+func bsKeyExpansion(key []byte, word []uint32) {
+	nk := len(key) / 4
+	for i := 0; i < nk; i++ {
+		word[i] = binary.BigEndian.Uint32(key[4*i : 4*(i+1)])
+	}
+	for i := nk; i < len(word); i++ {
+		tmp := word[i-1]
+		switch {
+		case i%nk == 0:
+			tmp = bsSubWord(rotWord(tmp)) ^ uint32(powx[i/nk-1])<<24
+		case nk > 6 && i%nk == 4:
+			tmp = bsSubWord(tmp)
+		default:
+		}
+		word[i] = word[i-nk] ^ tmp
+	}
+}
+
+func bsSubWord(w uint32) uint32 {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], w)
+	var q bitslice
+	q.load(b[:])
+	bsSbox(&q)
+	q.store(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// BlockSize implements cipher.Block
+func (*BitslicedAES) BlockSize() int { return 16 }
+
+// Encrypt implements cipher.Block
+func (c *BitslicedAES) Encrypt(dst, src []byte) {
+	if len(src) < 16 || len(dst) < 16 {
+		panic("invalid length")
+	}
+	c.EncryptBlocks(dst[:16], src[:16])
+}
+
+// Decrypt implements cipher.Block
+func (c *BitslicedAES) Decrypt(dst, src []byte) {
+	if len(src) < 16 || len(dst) < 16 {
+		panic("invalid length")
+	}
+	c.DecryptBlocks(dst[:16], src[:16])
+}
+
+// EncryptBlocks encrypts src, a multiple of the block size, into dst.
+// Every 4 blocks are encrypted at once.
+func (c *BitslicedAES) EncryptBlocks(dst, src []byte) {
+	c.cryptBlocks(dst, src, false)
+}
+
+// DecryptBlocks decrypts src, a multiple of the block size, into dst.
+// Every 4 blocks are decrypted at once.
+func (c *BitslicedAES) DecryptBlocks(dst, src []byte) {
+	c.cryptBlocks(dst, src, true)
+}
+
+func (c *BitslicedAES) cryptBlocks(dst, src []byte, decrypt bool) {
+	if len(src)%16 != 0 {
+		panic("input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var q bitslice
+	for len(src) > 0 {
+		n := min(len(src), 16*bsBlocks)
+		q.load(src[:n])
+		if decrypt {
+			c.decrypt(&q)
+		} else {
+			c.encrypt(&q)
+		}
+		q.store(dst[:n])
+		src, dst = src[n:], dst[n:]
+	}
+}
+
+func (c *BitslicedAES) encrypt(q *bitslice) {
+	nr := c.nr
+	q.xor(&c.rk[0])
+	for i := 1; i < nr; i++ {
+		bsSbox(q)
+		bsShiftRows(q, false)
+		bsMixColumns(q)
+		q.xor(&c.rk[i])
+	}
+	bsSbox(q)
+	bsShiftRows(q, false)
+	q.xor(&c.rk[nr])
+}
+
+func (c *BitslicedAES) decrypt(q *bitslice) {
+	nr := c.nr
+	q.xor(&c.rk[nr])
+	for i := nr - 1; i > 0; i-- {
+		bsShiftRows(q, true)
+		bsInvSbox(q)
+		q.xor(&c.rk[i])
+		bsInvMixColumns(q)
+	}
+	bsShiftRows(q, true)
+	bsInvSbox(q)
+	q.xor(&c.rk[0])
+}
@@ -0,0 +1,144 @@
+package toyaes
+
+import (
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_bsSbox(t *testing.T) {
+	t.Parallel()
+
+	in := make([]byte, 256)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	for _, tt := range []struct {
+		name string
+		f    func(*bitslice)
+		box  []byte
+	}{
+		{"sbox", bsSbox, sbox},
+		{"isbox", bsInvSbox, isbox},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]byte, 256)
+			for i := 0; i < 256; i += 64 {
+				var q bitslice
+				q.load(in[i : i+64])
+				tt.f(&q)
+				q.store(got[i : i+64])
+			}
+			if !reflect.DeepEqual(got, tt.box) {
+				t.Errorf("got=%X, want=%X", got, tt.box)
+			}
+		})
+	}
+}
+
+func Test_bsRoundFunctions(t *testing.T) {
+	t.Parallel()
+
+	src := make([]byte, 16*bsBlocks)
+	_, _ = rand.Read(src)
+	for _, tt := range []struct {
+		name string
+		bs   func(*bitslice)
+		ref  func(*[16]byte)
+	}{
+		{"shiftRows", func(q *bitslice) { bsShiftRows(q, false) }, shiftRows},
+		{"invShiftRows", func(q *bitslice) { bsShiftRows(q, true) }, invShiftRows},
+		{"mixColumns", bsMixColumns, mixColumns},
+		{"invMixColumns", bsInvMixColumns, invMixColumns},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			want := append([]byte(nil), src...)
+			for i := 0; i < len(want); i += 16 {
+				tt.ref((*[16]byte)(want[i : i+16]))
+			}
+			var q bitslice
+			q.load(src)
+			tt.bs(&q)
+			got := make([]byte, len(src))
+			q.store(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got=%X, want=%X", got, want)
+			}
+		})
+	}
+}
+
+func TestBitslicedAES(t *testing.T) {
+	t.Parallel()
+
+	for _, keySize := range []int{16, 24, 32} {
+		key := make([]byte, keySize)
+		for i := 0; i < 100; i++ {
+			_, _ = rand.Read(key)
+			ref := NewToyAES(key)
+			bs := NewBitslicedAES(key)
+
+			// 1 to 9 blocks to cover partial batches
+			n := 16 * (i%9 + 1)
+			src := make([]byte, n)
+			_, _ = rand.Read(src)
+
+			want := make([]byte, n)
+			for j := 0; j < n; j += 16 {
+				ref.Encrypt(want[j:j+16], src[j:j+16])
+			}
+			got := make([]byte, n)
+			bs.EncryptBlocks(got, src)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("key=%X: EncryptBlocks got=%X, want=%X", key, got, want)
+			}
+			one := make([]byte, 16)
+			bs.Encrypt(one, src[:16])
+			if !reflect.DeepEqual(one, want[:16]) {
+				t.Fatalf("key=%X: Encrypt got=%X, want=%X", key, one, want[:16])
+			}
+
+			for j := 0; j < n; j += 16 {
+				ref.Decrypt(want[j:j+16], src[j:j+16])
+			}
+			bs.DecryptBlocks(got, src)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("key=%X: DecryptBlocks got=%X, want=%X", key, got, want)
+			}
+			bs.Decrypt(one, src[:16])
+			if !reflect.DeepEqual(one, want[:16]) {
+				t.Fatalf("key=%X: Decrypt got=%X, want=%X", key, one, want[:16])
+			}
+		}
+	}
+}
+
+func BenchmarkEncryptBitsliced(b *testing.B) {
+	b.ReportAllocs()
+
+	key := make([]byte, 32)
+	plaintext := make([]byte, 16*bsBlocks)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(plaintext)
+
+	c := NewBitslicedAES(key)
+	ciphertext := make([]byte, len(plaintext))
+	b.SetBytes(int64(len(plaintext)))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		c.EncryptBlocks(ciphertext, plaintext)
+	}
+}
+
+func ExampleBitslicedAES_EncryptBlocks() {
+	c := NewBitslicedAES(make([]byte, 16))
+
+	// the 4 blocks are encrypted at once in the uint64 lanes
+	src := make([]byte, 4*16)
+	dst := make([]byte, len(src))
+	c.EncryptBlocks(dst, src)
+	fmt.Printf("%x\n", dst[3*16:])
+	// Output: 66e94bd4ef8a2c3b884cfa59ca342b2e
+}
@@ -4,13 +4,13 @@ import (
 	"crypto/aes"
 	ccipher "crypto/cipher"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
 )
 
 func Test_mul(t *testing.T) {
-	t.Skip()
 	t.Parallel()
 
 	type args struct {
@@ -23,7 +23,9 @@ func Test_mul(t *testing.T) {
 		want byte
 	}{
 		{name: "0x57 x 0x83 = c1", args: args{x: 0x57, y: 0x83}, want: 0xc1},
-		{name: "0x57 x 0x13 = f3", args: args{x: 0x57, y: 0x13}, want: 0xfe},
+		{name: "0x57 x 0x13 = fe", args: args{x: 0x57, y: 0x13}, want: 0xfe},
+		{name: "0x02 x 0x87 = 15", args: args{x: 0x02, y: 0x87}, want: 0x15},
+		{name: "0x0e x 0x01 = 0e", args: args{x: 0x0e, y: 0x01}, want: 0x0e},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -356,6 +358,7 @@ func TestCalcRcon(t *testing.T) {
 }
 
 func BenchmarkEncrypt(b *testing.B) {
+	b.ReportAllocs()
 
 	key := make([]byte, 32)
 	plaintext := make([]byte, 16)
@@ -373,6 +376,8 @@ func BenchmarkEncrypt(b *testing.B) {
 }
 
 func BenchmarkGoEncrypt(b *testing.B) {
+	b.ReportAllocs()
+
 	key := make([]byte, 32)
 	plaintext := make([]byte, 16)
 	_, _ = rand.Read(key)
@@ -387,3 +392,190 @@ func BenchmarkGoEncrypt(b *testing.B) {
 		gob.Decrypt(plaintext, ciphertext)
 	}
 }
+
+func Test_decKeyExpansion_128bit(t *testing.T) {
+	t.Parallel()
+
+	key := []byte{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c}
+	word := make([]uint32, 44)
+	keyExpansion(key, word)
+	dword := make([]uint32, 44)
+	decKeyExpansion(word, dword)
+
+	// first and last round keys are not transformed
+	if !reflect.DeepEqual(dword[:4], word[40:]) || !reflect.DeepEqual(dword[40:], word[:4]) {
+		t.Errorf("invalid first or last round key. got=%v, want=%v, %v.", dword, word[40:], word[:4])
+	}
+	// InvMixColumns of round key 9 (0xac7766f3, ...)
+	rk := [16]byte{0xac, 0x77, 0x66, 0xf3, 0x19, 0xfa, 0xdc, 0x21, 0x28, 0xd1, 0x29, 0x41, 0x57, 0x5c, 0x00, 0x6e}
+	invMixColumns(&rk)
+	want := []uint32{
+		uint32(rk[0])<<24 | uint32(rk[1])<<16 | uint32(rk[2])<<8 | uint32(rk[3]),
+		uint32(rk[4])<<24 | uint32(rk[5])<<16 | uint32(rk[6])<<8 | uint32(rk[7]),
+		uint32(rk[8])<<24 | uint32(rk[9])<<16 | uint32(rk[10])<<8 | uint32(rk[11]),
+		uint32(rk[12])<<24 | uint32(rk[13])<<16 | uint32(rk[14])<<8 | uint32(rk[15]),
+	}
+	if !reflect.DeepEqual(dword[4:8], want) {
+		t.Errorf("invalid round key. got=%v, want=%v.", dword[4:8], want)
+	}
+}
+
+func TestEquivalentInverseCipher(t *testing.T) {
+	t.Parallel()
+
+	for _, keySize := range []int{16, 24, 32} {
+		key := make([]byte, keySize)
+		src := make([]byte, 16)
+		for i := 0; i < 1000; i++ {
+			_, _ = rand.Read(key)
+			_, _ = rand.Read(src)
+
+			want := make([]byte, 16)
+			NewToyAES(key).Decrypt(want, src)
+
+			got := make([]byte, 16)
+			NewToyAES(key, WithEquivalentInverseCipher()).Decrypt(got, src)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("key=%X: got=%X, want=%X", key, got, want)
+			}
+		}
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	// AllocsPerRun does not work with t.Parallel
+
+	key := make([]byte, 32)
+	src := make([]byte, 16)
+	dst := make([]byte, 16)
+	word := make([]uint32, 60)
+	dword := make([]uint32, 60)
+
+	if n := testing.AllocsPerRun(100, func() { keyExpansion(key, word) }); n != 0 {
+		t.Errorf("keyExpansion allocates %v times", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { decKeyExpansion(word, dword) }); n != 0 {
+		t.Errorf("decKeyExpansion allocates %v times", n)
+	}
+	// only the returned cipher.Block itself
+	if n := testing.AllocsPerRun(100, func() { NewToyAES(key, WithTTables()) }); n != 1 {
+		t.Errorf("NewToyAES allocates %v times, want 1", n)
+	}
+
+	for name, c := range map[string]ccipher.Block{
+		"reference":  NewToyAES(key),
+		"ttables":    NewToyAES(key, WithTTables()),
+		"equivalent": NewToyAES(key, WithEquivalentInverseCipher()),
+		"bitsliced":  NewBitslicedAES(key),
+	} {
+		if n := testing.AllocsPerRun(100, func() { c.Encrypt(dst, src) }); n != 0 {
+			t.Errorf("%s: Encrypt allocates %v times", name, n)
+		}
+		if n := testing.AllocsPerRun(100, func() { c.Decrypt(dst, src) }); n != 0 {
+			t.Errorf("%s: Decrypt allocates %v times", name, n)
+		}
+	}
+}
+
+func BenchmarkNewToyAES(b *testing.B) {
+	b.ReportAllocs()
+
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		NewToyAES(key)
+	}
+}
+
+func BenchmarkEncryptBlock(b *testing.B) {
+	key := make([]byte, 32)
+	plaintext := make([]byte, 16)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(plaintext)
+	ciphertext := make([]byte, 16)
+
+	for name, c := range map[string]ccipher.Block{
+		"reference": NewToyAES(key),
+		"ttables":   NewToyAES(key, WithTTables()),
+		"bitsliced": NewBitslicedAES(key),
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(16)
+			for n := 0; n < b.N; n++ {
+				c.Encrypt(ciphertext, plaintext)
+			}
+		})
+	}
+}
+
+func TestNewToyAESWithError(t *testing.T) {
+	t.Parallel()
+
+	constructors := map[string]func([]byte) (ccipher.Block, error){
+		"toyAES": func(key []byte) (ccipher.Block, error) { return NewToyAESWithError(key) },
+		"bitsliced": func(key []byte) (ccipher.Block, error) {
+			c, err := NewBitslicedAESWithError(key)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+	}
+	for name, newBlock := range constructors {
+		for _, n := range []int{0, 1, 15, 17, 20, 23, 25, 31, 33, 64} {
+			_, err := newBlock(make([]byte, n))
+			var ke KeySizeError
+			if !errors.As(err, &ke) || int(ke) != n {
+				t.Errorf("%s: key size %d: err = %v, want KeySizeError(%d)", name, n, err, n)
+			}
+		}
+		for _, n := range []int{16, 24, 32} {
+			if _, err := newBlock(make([]byte, n)); err != nil {
+				t.Errorf("%s: key size %d: err = %v", name, n, err)
+			}
+		}
+	}
+}
+
+func TestNewToyAES_PanicKeySizeError(t *testing.T) {
+	t.Parallel()
+
+	for name, f := range map[string]func(){
+		"toyAES":    func() { NewToyAES(make([]byte, 20)) },
+		"bitsliced": func() { NewBitslicedAES(make([]byte, 20)) },
+	} {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); err != KeySizeError(20) {
+					t.Errorf("%s panics with %v, want KeySizeError(20)", name, err)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func FuzzNewToyAESWithError(f *testing.F) {
+	f.Add(make([]byte, 16))
+	f.Add(make([]byte, 17))
+	f.Fuzz(func(t *testing.T, key []byte) {
+		c, err := NewToyAESWithError(key)
+		if err != nil {
+			if _, ok := err.(KeySizeError); !ok {
+				t.Fatalf("err = %T, want KeySizeError", err)
+			}
+			return
+		}
+		want, _ := aes.NewCipher(key)
+		src := make([]byte, 16)
+		got, exp := make([]byte, 16), make([]byte, 16)
+		c.Encrypt(got, src)
+		want.Encrypt(exp, src)
+		if !reflect.DeepEqual(got, exp) {
+			t.Fatalf("Encrypt = %X, want %X", got, exp)
+		}
+	})
+}
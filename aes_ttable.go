@@ -0,0 +1,76 @@
+package toyaes
+
+import "encoding/binary"
+
+// cipherT is cipher using te0-te3.
+// A round of subBytes, shiftRows and mixColumns is 16 table lookups.
+func cipherT(input, out []byte, word []uint32) {
+	_ = input[15]
+	_ = out[15]
+	nr := nr(word)
+
+	s0 := binary.BigEndian.Uint32(input[0:4]) ^ word[0]
+	s1 := binary.BigEndian.Uint32(input[4:8]) ^ word[1]
+	s2 := binary.BigEndian.Uint32(input[8:12]) ^ word[2]
+	s3 := binary.BigEndian.Uint32(input[12:16]) ^ word[3]
+
+	k := nb
+	for i := 1; i < nr; i++ {
+		t0 := te0[s0>>24] ^ te1[s1>>16&0xff] ^ te2[s2>>8&0xff] ^ te3[s3&0xff] ^ word[k]
+		t1 := te0[s1>>24] ^ te1[s2>>16&0xff] ^ te2[s3>>8&0xff] ^ te3[s0&0xff] ^ word[k+1]
+		t2 := te0[s2>>24] ^ te1[s3>>16&0xff] ^ te2[s0>>8&0xff] ^ te3[s1&0xff] ^ word[k+2]
+		t3 := te0[s3>>24] ^ te1[s0>>16&0xff] ^ te2[s1>>8&0xff] ^ te3[s2&0xff] ^ word[k+3]
+		s0, s1, s2, s3 = t0, t1, t2, t3
+		k += nb
+	}
+
+	// last round has no mixColumns
+	t0 := subShiftWord(s0, s1, s2, s3, sbox) ^ word[k]
+	t1 := subShiftWord(s1, s2, s3, s0, sbox) ^ word[k+1]
+	t2 := subShiftWord(s2, s3, s0, s1, sbox) ^ word[k+2]
+	t3 := subShiftWord(s3, s0, s1, s2, sbox) ^ word[k+3]
+
+	binary.BigEndian.PutUint32(out[0:4], t0)
+	binary.BigEndian.PutUint32(out[4:8], t1)
+	binary.BigEndian.PutUint32(out[8:12], t2)
+	binary.BigEndian.PutUint32(out[12:16], t3)
+}
+
+// invCipherT is the equivalent inverse cipher using td0-td3.
+// dword is the decryption key schedule made by decKeyExpansion.
+func invCipherT(input, out []byte, dword []uint32) {
+	_ = input[15]
+	_ = out[15]
+	nr := nr(dword)
+
+	s0 := binary.BigEndian.Uint32(input[0:4]) ^ dword[0]
+	s1 := binary.BigEndian.Uint32(input[4:8]) ^ dword[1]
+	s2 := binary.BigEndian.Uint32(input[8:12]) ^ dword[2]
+	s3 := binary.BigEndian.Uint32(input[12:16]) ^ dword[3]
+
+	k := nb
+	for i := 1; i < nr; i++ {
+		t0 := td0[s0>>24] ^ td1[s3>>16&0xff] ^ td2[s2>>8&0xff] ^ td3[s1&0xff] ^ dword[k]
+		t1 := td0[s1>>24] ^ td1[s0>>16&0xff] ^ td2[s3>>8&0xff] ^ td3[s2&0xff] ^ dword[k+1]
+		t2 := td0[s2>>24] ^ td1[s1>>16&0xff] ^ td2[s0>>8&0xff] ^ td3[s3&0xff] ^ dword[k+2]
+		t3 := td0[s3>>24] ^ td1[s2>>16&0xff] ^ td2[s1>>8&0xff] ^ td3[s0&0xff] ^ dword[k+3]
+		s0, s1, s2, s3 = t0, t1, t2, t3
+		k += nb
+	}
+
+	// last round has no invMixColumns
+	t0 := subShiftWord(s0, s3, s2, s1, isbox) ^ dword[k]
+	t1 := subShiftWord(s1, s0, s3, s2, isbox) ^ dword[k+1]
+	t2 := subShiftWord(s2, s1, s0, s3, isbox) ^ dword[k+2]
+	t3 := subShiftWord(s3, s2, s1, s0, isbox) ^ dword[k+3]
+
+	binary.BigEndian.PutUint32(out[0:4], t0)
+	binary.BigEndian.PutUint32(out[4:8], t1)
+	binary.BigEndian.PutUint32(out[8:12], t2)
+	binary.BigEndian.PutUint32(out[12:16], t3)
+}
+
+// subShiftWord takes row i of the column from wi and substitutes it by box
+func subShiftWord(w0, w1, w2, w3 uint32, box []byte) uint32 {
+	return uint32(box[w0>>24])<<24 | uint32(box[w1>>16&0xff])<<16 | uint32(box[w2>>8&0xff])<<8 | uint32(box[w3&0xff])
+}
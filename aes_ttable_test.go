@@ -0,0 +1,74 @@
+package toyaes
+
+import (
+	"crypto/rand"
+	"reflect"
+	"testing"
+)
+
+func TestTTables_crossCheck(t *testing.T) {
+	t.Parallel()
+
+	for _, keySize := range []int{16, 24, 32} {
+		key := make([]byte, keySize)
+		src := make([]byte, 16)
+		for i := 0; i < 1000; i++ {
+			_, _ = rand.Read(key)
+			_, _ = rand.Read(src)
+
+			ref := NewToyAES(key)
+			fast := NewToyAES(key, WithTTables())
+
+			want := make([]byte, 16)
+			got := make([]byte, 16)
+			ref.Encrypt(want, src)
+			fast.Encrypt(got, src)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("key=%X: Encrypt got=%X, want=%X", key, got, want)
+			}
+
+			ref.Decrypt(want, src)
+			fast.Decrypt(got, src)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("key=%X: Decrypt got=%X, want=%X", key, got, want)
+			}
+		}
+	}
+}
+
+func TestTTables_CipherExample(t *testing.T) {
+	t.Parallel()
+	// Appendix B – Cipher Example
+	src := []byte{0x32, 0x43, 0xf6, 0xa8, 0x88, 0x5a, 0x30, 0x8d, 0x31, 0x31, 0x98, 0xa2, 0xe0, 0x37, 0x07, 0x34}
+	key := []byte{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c}
+	want := []byte{0x39, 0x25, 0x84, 0x1d, 0x02, 0xdc, 0x09, 0xfb, 0xdc, 0x11, 0x85, 0x97, 0x19, 0x6a, 0x0b, 0x32}
+
+	c := NewToyAES(key, WithTTables())
+	got := make([]byte, 16)
+	c.Encrypt(got, src)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid cipher text. got=%v, want=%v.", got, want)
+	}
+	c.Decrypt(got, want)
+	if !reflect.DeepEqual(got, src) {
+		t.Errorf("invalid plain text. got=%v, want=%v.", got, src)
+	}
+}
+
+func BenchmarkEncryptTTables(b *testing.B) {
+	b.ReportAllocs()
+
+	key := make([]byte, 32)
+	plaintext := make([]byte, 16)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(plaintext)
+
+	ciphertext := make([]byte, 16)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		t := NewToyAES(key, WithTTables())
+		t.Encrypt(ciphertext, plaintext)
+		t.Decrypt(plaintext, ciphertext)
+	}
+}
@@ -0,0 +1,71 @@
+package toyaes
+
+import "unsafe" //nolint:gosec // only the addresses are compared
+
+// anyOverlap reports whether x and y share memory at any index
+func anyOverlap(x, y []byte) bool {
+	return len(x) > 0 && len(y) > 0 &&
+		uintptr(unsafe.Pointer(&x[0])) <= uintptr(unsafe.Pointer(&y[len(y)-1])) &&
+		uintptr(unsafe.Pointer(&y[0])) <= uintptr(unsafe.Pointer(&x[len(x)-1]))
+}
+
+// inexactOverlap reports whether x and y share memory at any non-corresponding
+// index. In-place operation, where x and y are the same memory, is allowed.
+func inexactOverlap(x, y []byte) bool {
+	if len(x) == 0 || len(y) == 0 || &x[0] == &y[0] {
+		return false
+	}
+	return anyOverlap(x, y)
+}
+
+// sliceForAppend extends in by n bytes. head is the whole slice and tail is
+// the last n bytes. The capacity of in is reused if possible.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+// sealShifted prepares Seal of an AEAD whose output is n bytes of prefix
+// followed by the output of an inner AEAD. Exact in-place use, where dst is
+// plaintext[:0], is allowed as in crypto/cipher: plaintext is moved to its
+// place in the output first, so in is the input for the inner Seal in place
+// at ciphertext.
+func sealShifted(dst, plaintext, additionalData []byte, n, overhead int) (ret, prefix, ciphertext, in []byte) {
+	ret, out := sliceForAppend(dst, len(plaintext)+overhead)
+	if inexactOverlap(out, plaintext) || anyOverlap(out, additionalData) {
+		panic("toyaes: invalid buffer overlap")
+	}
+	prefix, ciphertext = out[:n], out[n:]
+	copy(ciphertext, plaintext)
+	return ret, prefix, ciphertext, ciphertext[:len(plaintext)]
+}
+
+// openShifted returns the input for the inner Open of an AEAD whose
+// ciphertext has n bytes of prefix, for plaintext of size m appended to dst.
+// For exact in-place use, where dst is ciphertext[:0], the rest of ciphertext
+// is moved to the start so that the inner Open decrypts in place.
+// The prefix must be copied before, as it is overwritten then.
+func openShifted(dst, ciphertext, additionalData []byte, n, m int) []byte {
+	body := ciphertext[n:]
+	if cap(dst)-len(dst) < m || m == 0 {
+		// sliceForAppend allocates, or there is no output
+		return body
+	}
+	out := dst[len(dst) : len(dst)+m]
+	if anyOverlap(out, additionalData) {
+		panic("toyaes: invalid buffer overlap")
+	}
+	if &out[0] != &ciphertext[0] {
+		// the inner Open checks any other overlap
+		return body
+	}
+	shifted := ciphertext[:len(body)]
+	copy(shifted, body)
+	return shifted
+}
@@ -0,0 +1,165 @@
+package toyaes
+
+import (
+	ccipher "crypto/cipher"
+	"crypto/subtle"
+)
+
+// CSVariant is the order of the last two blocks in CBC with ciphertext stealing.
+//
+// See: https://csrc.nist.gov/pubs/sp/800/38/a/addendum/final
+type CSVariant int
+
+const (
+	// CS1 keeps the order of CBC: ..., C(n-1)*, C(n)
+	CS1 CSVariant = iota + 1
+	// CS2 swaps the last two blocks only if the last block is partial
+	CS2
+	// CS3 always swaps the last two blocks: ..., C(n), C(n-1)*.
+	// It is used by Kerberos (RFC 3962).
+	CS3
+)
+
+// CBCCS encrypts or decrypts whole messages in CBC mode with ciphertext
+// stealing, so the ciphertext has the same length as the plaintext.
+type CBCCS struct {
+	b       ccipher.Block
+	iv      []byte
+	variant CSVariant
+	decrypt bool
+}
+
+// NewCBCCSEncrypter returns a CBCCS which encrypts with b, iv and variant
+func NewCBCCSEncrypter(b ccipher.Block, iv []byte, variant CSVariant) *CBCCS {
+	return newCBCCS(b, iv, variant, false)
+}
+
+// NewCBCCSDecrypter returns a CBCCS which decrypts with b, iv and variant
+func NewCBCCSDecrypter(b ccipher.Block, iv []byte, variant CSVariant) *CBCCS {
+	return newCBCCS(b, iv, variant, true)
+}
+
+func newCBCCS(b ccipher.Block, iv []byte, variant CSVariant, decrypt bool) *CBCCS {
+	if len(iv) != b.BlockSize() {
+		panic("toyaes: IV length must equal block size")
+	}
+	if variant < CS1 || variant > CS3 {
+		panic("toyaes: invalid ciphertext stealing variant")
+	}
+	return &CBCCS{
+		b:       b,
+		iv:      append([]byte(nil), iv...),
+		variant: variant,
+		decrypt: decrypt,
+	}
+}
+
+// BlockSize returns the block size of the cipher
+func (x *CBCCS) BlockSize() int { return x.b.BlockSize() }
+
+// CryptMessage encrypts or decrypts src into dst. src is a whole message of
+// at least one block, and dst must be at least as long as src.
+func (x *CBCCS) CryptMessage(dst, src []byte) {
+	bs := x.b.BlockSize()
+	if len(src) < bs {
+		panic("toyaes: CBC-CS input is shorter than a block")
+	}
+	if len(dst) < len(src) {
+		panic("toyaes: output smaller than input")
+	}
+	if x.decrypt {
+		x.decryptMessage(dst[:len(src)], src)
+	} else {
+		x.encryptMessage(dst[:len(src)], src)
+	}
+}
+
+// swap reports whether the last two blocks are swapped when the last block has d bytes
+func (x *CBCCS) swap(d int) bool {
+	switch x.variant {
+	case CS1:
+		return false
+	case CS2:
+		return d != x.b.BlockSize()
+	default:
+		return true
+	}
+}
+
+func (x *CBCCS) encryptMessage(dst, src []byte) {
+	bs := x.b.BlockSize()
+	// n blocks and the last one has d bytes
+	n := (len(src) + bs - 1) / bs
+	d := len(src) - (n-1)*bs
+
+	prev := x.iv
+	block := make([]byte, bs)
+	cs := make([]byte, n*bs)
+	for i := 0; i < n; i++ {
+		clear(block)
+		copy(block, src[i*bs:])
+		subtle.XORBytes(block, block, prev)
+		x.b.Encrypt(cs[i*bs:(i+1)*bs], block)
+		prev = cs[i*bs : (i+1)*bs]
+	}
+	if n == 1 {
+		copy(dst, cs)
+		return
+	}
+
+	// C1, ..., C(n-2)
+	copy(dst, cs[:(n-2)*bs])
+	prevLast := cs[(n-2)*bs : (n-2)*bs+d] // C(n-1)*
+	last := cs[(n-1)*bs:]                 // C(n)
+	tail := dst[(n-2)*bs:]
+	if x.swap(d) {
+		copy(tail, last)
+		copy(tail[bs:], prevLast)
+	} else {
+		copy(tail, prevLast)
+		copy(tail[d:], last)
+	}
+}
+
+func (x *CBCCS) decryptMessage(dst, src []byte) {
+	bs := x.b.BlockSize()
+	n := (len(src) + bs - 1) / bs
+	d := len(src) - (n-1)*bs
+
+	if n == 1 {
+		x.b.Decrypt(dst, src)
+		subtle.XORBytes(dst, dst, x.iv)
+		return
+	}
+
+	tail := src[(n-2)*bs:]
+	var prevLast, last []byte // C(n-1)*, C(n)
+	if x.swap(d) {
+		last, prevLast = tail[:bs], tail[bs:]
+	} else {
+		prevLast, last = tail[:d], tail[d:]
+	}
+
+	// D(C(n)) = C(n-1) xor (P(n)* || 0), so the stolen bytes of C(n-1) are recovered from it
+	z := make([]byte, bs)
+	x.b.Decrypt(z, last)
+	cn1 := make([]byte, bs)
+	copy(cn1, prevLast)
+	copy(cn1[d:], z[d:])
+
+	// decrypt into a buffer because dst may overlap src
+	out := make([]byte, len(src))
+	subtle.XORBytes(out[(n-1)*bs:], z[:d], prevLast)
+
+	prev := x.iv
+	for i := 0; i < n-1; i++ {
+		c := src[i*bs : (i+1)*bs]
+		if i == n-2 {
+			c = cn1
+		}
+		x.b.Decrypt(out[i*bs:(i+1)*bs], c)
+		subtle.XORBytes(out[i*bs:(i+1)*bs], out[i*bs:(i+1)*bs], prev)
+		prev = c
+	}
+	copy(dst, out)
+}
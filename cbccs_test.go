@@ -0,0 +1,101 @@
+package toyaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCBCCS3_RFC3962(t *testing.T) {
+	t.Parallel()
+
+	// RFC 3962 Appendix B
+	key := []byte("chicken teriyaki")
+	iv := make([]byte, 16)
+	const input = "I would like the General Gau's Chicken, please, and wonton soup."
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{17, "c6353568f2bf8cb4d8a580362da7ff7f97"},
+		{31, "fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5"},
+		{32, "39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584"},
+		{47, "97687268d6ecccc0c07b25e25ecfe584b3fffd940c16a18c1b5549d2f838029e39312523a78662d5be7fcbcc98ebf5"},
+		{48, "97687268d6ecccc0c07b25e25ecfe5849dad8bbb96c4cdc03bc103e1a194bbd839312523a78662d5be7fcbcc98ebf5a8"},
+		{64, "97687268d6ecccc0c07b25e25ecfe58439312523a78662d5be7fcbcc98ebf5a84807efe836ee89a526730dbc2f7bc8409dad8bbb96c4cdc03bc103e1a194bbd8"},
+	}
+	for _, tt := range tests {
+		pt := []byte(input[:tt.n])
+		got := make([]byte, len(pt))
+		NewCBCCSEncrypter(NewToyAES(key), iv, CS3).CryptMessage(got, pt)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("len=%d: got=%x, want=%s", tt.n, got, tt.want)
+		}
+		dec := make([]byte, len(got))
+		NewCBCCSDecrypter(NewToyAES(key), iv, CS3).CryptMessage(dec, got)
+		if !bytes.Equal(dec, pt) {
+			t.Errorf("len=%d: decrypted=%q, want=%q", tt.n, dec, pt)
+		}
+	}
+}
+
+func TestCBCCS_variants(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	iv := make([]byte, 16)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(iv)
+	block := NewToyAES(key)
+	gob, _ := aes.NewCipher(key)
+
+	for n := 16; n <= 80; n++ {
+		pt := make([]byte, n)
+		_, _ = rand.Read(pt)
+
+		cs := map[CSVariant][]byte{}
+		for _, v := range []CSVariant{CS1, CS2, CS3} {
+			ct := make([]byte, n)
+			NewCBCCSEncrypter(block, iv, v).CryptMessage(ct, pt)
+			cs[v] = bytes.Clone(ct)
+
+			got := make([]byte, n)
+			NewCBCCSDecrypter(block, iv, v).CryptMessage(got, ct)
+			if !bytes.Equal(got, pt) {
+				t.Fatalf("CS%d len=%d: decrypted=%X, want=%X", v, n, got, pt)
+			}
+			// in place
+			NewCBCCSDecrypter(block, iv, v).CryptMessage(ct, ct)
+			if !bytes.Equal(ct, pt) {
+				t.Fatalf("CS%d len=%d: in-place decrypted=%X, want=%X", v, n, ct, pt)
+			}
+		}
+
+		if n%16 == 0 {
+			// CS1 and CS2 are CBC for whole blocks
+			want := make([]byte, n)
+			ccipher.NewCBCEncrypter(gob, iv).CryptBlocks(want, pt)
+			if !bytes.Equal(cs[CS1], want) || !bytes.Equal(cs[CS2], want) {
+				t.Fatalf("len=%d: CS1=%X, CS2=%X, want=%X", n, cs[CS1], cs[CS2], want)
+			}
+		} else if !bytes.Equal(cs[CS2], cs[CS3]) {
+			// CS2 and CS3 are the same for a partial last block
+			t.Fatalf("len=%d: CS2=%X, CS3=%X", n, cs[CS2], cs[CS3])
+		}
+	}
+}
+
+func TestCBCCS_short(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("15 bytes input does not panic")
+		}
+	}()
+	x := NewCBCCSEncrypter(NewToyAES(make([]byte, 16)), make([]byte, 16), CS1)
+	x.CryptMessage(make([]byte, 15), make([]byte, 15))
+}
@@ -0,0 +1,52 @@
+// Command paddingoracle runs the padding oracle attack against an in-process
+// server and narrates each step.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/blck-snwmn/toyaes/lab/paddingoracle"
+)
+
+func main() {
+	message := flag.String("m", "attack at dawn! bring the secret documents.", "message encrypted by the server")
+	verbose := flag.Bool("v", false, "print every recovered byte")
+	flag.Parse()
+
+	server, err := paddingoracle.NewServer(paddingoracle.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ct, err := server.Encrypt([]byte(*message))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("server encrypted a secret message with a random key\n")
+	fmt.Printf("  IV || ciphertext = %s\n", hex.EncodeToString(ct))
+	fmt.Printf("the server answers whether the padding of a ciphertext is valid\n\n")
+
+	attacker := &paddingoracle.Attacker{
+		Oracle: server.Oracle,
+		Observe: func(s paddingoracle.Step) {
+			if s.Index == 15 {
+				fmt.Printf("block %d:\n", s.Block)
+			}
+			if *verbose {
+				fmt.Printf("  byte %2d: D(C)=%02x, P=%02x %q (queries=%d)\n",
+					s.Index, s.Intermediate, s.Plaintext, s.Plaintext, s.Queries)
+			}
+			if s.Index == 0 {
+				fmt.Printf("  done after %d queries\n", s.Queries)
+			}
+		},
+	}
+	pt, err := attacker.Decrypt(ct)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\nrecovered plaintext: %q\n", pt)
+	fmt.Printf("oracle queries: %d\n", attacker.Queries())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The file starts with the header below, followed by a stream of the stream
+// package, which has its own HKDF salt and nonce prefix. The header is the
+// associated data of the stream, so any change of it fails decryption.
+//
+//	magic       6 bytes  "TOYAES"
+//	version     1 byte   1
+//	algorithm   1 byte   algAES256GCMStream
+//	kdf         1 byte   kdfRawKey or kdfPBKDF2
+//	iterations  4 bytes  PBKDF2 iterations, big-endian, 0 for kdfRawKey
+//	salt       16 bytes  PBKDF2 salt, random also for kdfRawKey
+const (
+	magic      = "TOYAES"
+	version    = 1
+	saltSize   = 16
+	headerSize = len(magic) + 3 + 4 + saltSize
+)
+
+const (
+	// algAES256GCMStream is stream.NewEncryptingWriter with a 32-byte key
+	// and the default segment size
+	algAES256GCMStream = 1
+)
+
+const (
+	kdfRawKey = 0
+	kdfPBKDF2 = 1
+)
+
+const (
+	keySize = 32
+	// defaultIterations is the PBKDF2-HMAC-SHA256 iterations recommended by OWASP
+	defaultIterations = 600_000
+	// maxIterations bounds the work a crafted file can demand
+	maxIterations = 100_000_000
+)
+
+var errFormat = errors.New("not a toyaes file")
+
+type header struct {
+	kdf        byte
+	iterations uint32
+	salt       [saltSize]byte
+}
+
+// newHeader returns a header with a random salt
+func newHeader(kdf byte, iterations uint32) (header, error) {
+	h := header{kdf: kdf, iterations: iterations}
+	_, err := rand.Read(h.salt[:])
+	return h, err
+}
+
+func (h header) marshal() []byte {
+	b := make([]byte, 0, headerSize)
+	b = append(b, magic...)
+	b = append(b, version, algAES256GCMStream, h.kdf)
+	b = binary.BigEndian.AppendUint32(b, h.iterations)
+	return append(b, h.salt[:]...)
+}
+
+// readHeader reads a header and returns it with its encoding
+func readHeader(r io.Reader) (header, []byte, error) {
+	b := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return header{}, nil, errFormat
+		}
+		return header{}, nil, err
+	}
+	if string(b[:len(magic)]) != magic {
+		return header{}, nil, errFormat
+	}
+	p := b[len(magic):]
+	if p[0] != version {
+		return header{}, nil, fmt.Errorf("unsupported version %d", p[0])
+	}
+	if p[1] != algAES256GCMStream {
+		return header{}, nil, fmt.Errorf("unsupported algorithm %d", p[1])
+	}
+	h := header{kdf: p[2], iterations: binary.BigEndian.Uint32(p[3:])}
+	copy(h.salt[:], p[7:])
+	switch h.kdf {
+	case kdfRawKey:
+	case kdfPBKDF2:
+		if h.iterations == 0 || h.iterations > maxIterations {
+			return header{}, nil, fmt.Errorf("invalid PBKDF2 iterations %d", h.iterations)
+		}
+	default:
+		return header{}, nil, fmt.Errorf("unsupported key derivation %d", h.kdf)
+	}
+	return h, b, nil
+}
+
+// key returns the key for the header from a raw key or a password
+func (h header) key(raw []byte, password string) ([]byte, error) {
+	switch h.kdf {
+	case kdfRawKey:
+		if raw == nil {
+			return nil, errors.New("the file is encrypted with a key file; use -k")
+		}
+		return raw, nil
+	case kdfPBKDF2:
+		if raw != nil {
+			return nil, errors.New("the file is encrypted with a password; use -p")
+		}
+		return pbkdf2.Key(sha256.New, password, h.salt[:], int(h.iterations), keySize)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %d", h.kdf)
+	}
+}
@@ -0,0 +1,159 @@
+// Command toyaes encrypts and decrypts files with AES-256-GCM of this module,
+// streaming from stdin to stdout.
+//
+//	toyaes encrypt -k key.bin < plain > plain.toyaes
+//	toyaes decrypt -p password.txt < plain.toyaes > plain
+//
+// A key file holds a raw 32-byte key, e.g. made by
+// "head -c 32 /dev/urandom > key.bin". A password is read from the file
+// given by -p, or from the environment variable TOYAES_PASSWORD, and is
+// stretched by PBKDF2-HMAC-SHA256.
+//
+// The body is split into segments which are authenticated one by one, so
+// decrypt writes plaintext before the whole input is verified. If it fails,
+// discard the output.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blck-snwmn/toyaes/stream"
+)
+
+const passwordEnv = "TOYAES_PASSWORD"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "toyaes:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() error {
+	return errors.New("usage: toyaes encrypt|decrypt [-k keyfile | -p passwordfile] < input > output")
+}
+
+func run(args []string, in io.Reader, out io.Writer) error {
+	if len(args) == 0 {
+		return usage()
+	}
+	cmd, args := args[0], args[1:]
+	if cmd != "encrypt" && cmd != "decrypt" {
+		return usage()
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	keyFile := fs.String("k", "", "file of a raw 32-byte key")
+	passwordFile := fs.String("p", "", "file of a password (default $"+passwordEnv+")")
+	iterations := fs.Uint("iter", defaultIterations, "PBKDF2 iterations for encrypt with a password")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usage()
+	}
+	raw, password, err := readSecret(*keyFile, *passwordFile)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(out)
+	if cmd == "encrypt" {
+		// iterations are used only for a password
+		if raw == nil && (*iterations == 0 || *iterations > maxIterations) {
+			return fmt.Errorf("-iter must be between 1 and %d", maxIterations)
+		}
+		err = encrypt(w, in, raw, password, uint32(*iterations))
+	} else {
+		err = decrypt(w, in, raw, password)
+	}
+	if err != nil {
+		// write what is verified so far, as the error says the output is incomplete
+		_ = w.Flush()
+		return err
+	}
+	return w.Flush()
+}
+
+// readSecret returns the raw key from keyFile, or else the password.
+// raw is nil if a password is used.
+func readSecret(keyFile, passwordFile string) (raw []byte, password string, err error) {
+	if keyFile != "" && passwordFile != "" {
+		return nil, "", errors.New("-k and -p are exclusive")
+	}
+	if keyFile != "" {
+		raw, err = os.ReadFile(keyFile)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(raw) != keySize {
+			return nil, "", fmt.Errorf("key file must be %d bytes, got %d", keySize, len(raw))
+		}
+		return raw, "", nil
+	}
+	if passwordFile != "" {
+		b, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, "", err
+		}
+		password = strings.TrimRight(string(b), "\r\n")
+	} else {
+		password = os.Getenv(passwordEnv)
+	}
+	if password == "" {
+		return nil, "", errors.New("no key: use -k, -p or $" + passwordEnv)
+	}
+	return nil, password, nil
+}
+
+func encrypt(out io.Writer, in io.Reader, raw []byte, password string, iterations uint32) error {
+	kdf := byte(kdfPBKDF2)
+	if raw != nil {
+		kdf, iterations = kdfRawKey, 0
+	}
+	h, err := newHeader(kdf, iterations)
+	if err != nil {
+		return err
+	}
+	key, err := h.key(raw, password)
+	if err != nil {
+		return err
+	}
+	hb := h.marshal()
+	if _, err := out.Write(hb); err != nil {
+		return err
+	}
+	w, err := stream.NewEncryptingWriter(out, key, hb)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func decrypt(out io.Writer, in io.Reader, raw []byte, password string) error {
+	h, hb, err := readHeader(in)
+	if err != nil {
+		return err
+	}
+	key, err := h.key(raw, password)
+	if err != nil {
+		return err
+	}
+	r, err := stream.NewDecryptingReader(in, key, hb)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("output is incomplete and must be discarded: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blck-snwmn/toyaes"
+	"github.com/blck-snwmn/toyaes/stream"
+)
+
+// writeFile writes b to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newKeyFile(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, keySize)
+	_, _ = rand.Read(key)
+	return writeFile(t, "key.bin", key)
+}
+
+func runBytes(args []string, in []byte) ([]byte, error) {
+	var out bytes.Buffer
+	err := run(args, bytes.NewReader(in), &out)
+	return out.Bytes(), err
+}
+
+func mustEncrypt(t *testing.T, plaintext []byte, args ...string) []byte {
+	t.Helper()
+	ct, err := runBytes(append([]string{"encrypt"}, args...), plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestRun_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	keyFile := newKeyFile(t)
+	passwordFile := writeFile(t, "password.txt", []byte("correct horse\n"))
+	// spans several segments
+	plaintext := make([]byte, 3*stream.DefaultSegmentSize+100)
+	_, _ = rand.Read(plaintext)
+
+	tests := []struct {
+		name string
+		enc  []string
+		dec  []string
+	}{
+		{name: "key file", enc: []string{"-k", keyFile}, dec: []string{"-k", keyFile}},
+		// -iter is ignored with a key file
+		{name: "key file with -iter 0", enc: []string{"-k", keyFile, "-iter", "0"}, dec: []string{"-k", keyFile}},
+		{name: "password", enc: []string{"-p", passwordFile, "-iter", "10"}, dec: []string{"-p", passwordFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ct := mustEncrypt(t, plaintext, tt.enc...)
+			if !bytes.HasPrefix(ct, []byte(magic)) {
+				t.Fatalf("output does not start with the magic: %q", ct[:len(magic)])
+			}
+			got, err := runBytes(append([]string{"decrypt"}, tt.dec...), ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Error("decrypted data differs from the plaintext")
+			}
+		})
+	}
+}
+
+func TestRun_Reject(t *testing.T) {
+	t.Parallel()
+
+	keyFile := newKeyFile(t)
+	passwordFile := writeFile(t, "password.txt", []byte("correct horse"))
+	wrongPasswordFile := writeFile(t, "wrong.txt", []byte("battery staple"))
+	plaintext := make([]byte, stream.DefaultSegmentSize+100)
+	_, _ = rand.Read(plaintext)
+
+	byKey := mustEncrypt(t, plaintext, "-k", keyFile)
+	byPassword := mustEncrypt(t, plaintext, "-p", passwordFile, "-iter", "10")
+
+	modify := func(b []byte, f func([]byte)) []byte {
+		b = bytes.Clone(b)
+		f(b)
+		return b
+	}
+	setIterations := func(n uint32) func([]byte) {
+		return func(b []byte) { binary.BigEndian.PutUint32(b[len(magic)+3:], n) }
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		input []byte
+		// want is the error wanted by errors.Is, or nil for any error
+		want error
+	}{
+		{name: "wrong password", args: []string{"-p", wrongPasswordFile}, input: byPassword, want: toyaes.ErrOpen},
+		{name: "password for a key file", args: []string{"-p", passwordFile}, input: byKey},
+		{name: "key file for a password", args: []string{"-k", keyFile}, input: byPassword},
+		{name: "bad magic", args: []string{"-k", keyFile}, input: modify(byKey, func(b []byte) { b[0] ^= 1 }), want: errFormat},
+		{name: "not a file of this tool", args: []string{"-k", keyFile}, input: plaintext, want: errFormat},
+		{name: "empty", args: []string{"-k", keyFile}, input: nil, want: errFormat},
+		{name: "bad version", args: []string{"-k", keyFile}, input: modify(byKey, func(b []byte) { b[len(magic)] = version + 1 })},
+		{name: "bad algorithm", args: []string{"-k", keyFile}, input: modify(byKey, func(b []byte) { b[len(magic)+1] = 0 })},
+		{name: "bad kdf", args: []string{"-k", keyFile}, input: modify(byKey, func(b []byte) { b[len(magic)+2] = 9 })},
+		{name: "iterations 0", args: []string{"-p", passwordFile}, input: modify(byPassword, setIterations(0))},
+		{name: "iterations above the limit", args: []string{"-p", passwordFile}, input: modify(byPassword, setIterations(maxIterations+1))},
+		// the salt does not change a raw key, so only the associated data detects it
+		{name: "tampered salt", args: []string{"-k", keyFile}, input: modify(byKey, func(b []byte) { b[headerSize-1] ^= 1 }), want: toyaes.ErrOpen},
+		{name: "tampered iterations", args: []string{"-p", passwordFile}, input: modify(byPassword, setIterations(11)), want: toyaes.ErrOpen},
+		{name: "truncated body", args: []string{"-k", keyFile}, input: byKey[:len(byKey)-1], want: toyaes.ErrOpen},
+		{name: "truncated at a segment boundary", args: []string{"-k", keyFile}, input: byKey[:headerSize+stream.HeaderSize+stream.DefaultSegmentSize], want: toyaes.ErrOpen},
+		{name: "header only", args: []string{"-k", keyFile}, input: byKey[:headerSize], want: stream.ErrHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := runBytes(append([]string{"decrypt"}, tt.args...), tt.input)
+			if err == nil {
+				t.Fatal("decrypt succeeds")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_Args(t *testing.T) {
+	t.Parallel()
+
+	keyFile := newKeyFile(t)
+	passwordFile := writeFile(t, "password.txt", []byte("correct horse"))
+	shortKeyFile := writeFile(t, "short.bin", make([]byte, 16))
+	emptyPasswordFile := writeFile(t, "empty.txt", []byte("\n"))
+
+	for _, args := range [][]string{
+		nil,
+		{"sign"},
+		{"encrypt", "-k", keyFile, "extra"},
+		{"encrypt", "-k", keyFile, "-p", passwordFile},
+		{"encrypt", "-k", shortKeyFile},
+		{"encrypt", "-k", filepath.Join(t.TempDir(), "missing")},
+		{"encrypt", "-p", emptyPasswordFile},
+		{"encrypt", "-p", passwordFile, "-iter", "0"},
+		{"encrypt", "-p", passwordFile, "-iter", "100000001"},
+	} {
+		if _, err := runBytes(args, []byte("plaintext")); err == nil {
+			t.Errorf("%q succeeds", args)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package toyaes
+
+import (
+	ccipher "crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// commitmentSize is the size of the key commitment prefixed to the ciphertext
+const commitmentSize = sha256.Size
+
+const (
+	commitEncLabel    = "toyaes key-committing GCM encryption key"
+	commitCommitLabel = "toyaes key-committing GCM commitment"
+)
+
+var _ ccipher.AEAD = (*committingGCM)(nil)
+
+// committingGCM is GCM whose ciphertext is prefixed with a commitment to the key
+type committingGCM struct {
+	key []byte
+	gcm *toyGCM
+}
+
+// NewKeyCommittingGCM returns a key-committing AEAD over GCM.
+//
+// GCM alone is not key-committing: a ciphertext can be crafted to open
+// under many keys, which partitioning oracle attacks exploit to test a set
+// of keys with one query. Here the GCM key and a 32-byte commitment are
+// derived from key by HMAC-SHA256:
+//
+//	encKey     = HMAC(key, "...encryption key")[:len(key)]
+//	commitment = HMAC(key, "...commitment" || nonce)
+//
+// Seal outputs commitment || GCM(encKey, nonce, plaintext, additionalData),
+// and Open checks the commitment before GCM. Finding two keys with the same
+// commitment requires a collision of HMAC-SHA256, so a ciphertext opens
+// under at most one key.
+func NewKeyCommittingGCM(key []byte, opts ...GCMOption) (ccipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, KeySizeError(len(key))
+	}
+	block, err := NewToyAESWithError(commitEncKey(key))
+	if err != nil {
+		return nil, err
+	}
+	g, err := newGCM(block, gcmStandardNonceSize, gcmTagSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &committingGCM{key: append([]byte(nil), key...), gcm: g.(*toyGCM)}, nil
+}
+
+// commitEncKey derives the GCM key of NewKeyCommittingGCM
+func commitEncKey(key []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(commitEncLabel))
+	return m.Sum(nil)[:len(key)]
+}
+
+// commitment returns the commitment to the key and nonce
+func (c *committingGCM) commitment(nonce []byte) []byte {
+	m := hmac.New(sha256.New, c.key)
+	m.Write([]byte(commitCommitLabel))
+	m.Write(nonce)
+	return m.Sum(nil)
+}
+
+// NonceSize implements cipher.AEAD
+func (c *committingGCM) NonceSize() int { return c.gcm.NonceSize() }
+
+// Overhead implements cipher.AEAD
+func (c *committingGCM) Overhead() int { return commitmentSize + c.gcm.Overhead() }
+
+// Seal implements cipher.AEAD
+func (c *committingGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.NonceSize() {
+		panic("toyaes: incorrect nonce length given to GCM")
+	}
+	commitment := c.commitment(nonce)
+	ret, prefix, ciphertext, in := sealShifted(dst, plaintext, additionalData, commitmentSize, c.Overhead())
+	copy(prefix, commitment)
+	c.gcm.Seal(ciphertext[:0], nonce, in, additionalData)
+	return ret
+}
+
+// Open implements cipher.AEAD
+func (c *committingGCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.NonceSize() {
+		return nil, NonceSizeError(len(nonce))
+	}
+	if len(ciphertext) < c.Overhead() {
+		return nil, ErrOpen
+	}
+	if subtle.ConstantTimeCompare(c.commitment(nonce), ciphertext[:commitmentSize]) != 1 {
+		return nil, ErrOpen
+	}
+	body := openShifted(dst, ciphertext, additionalData, commitmentSize, len(ciphertext)-c.Overhead())
+	return c.gcm.Open(dst, nonce, body, additionalData)
+}
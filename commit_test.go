@@ -0,0 +1,160 @@
+package toyaes
+
+import (
+	"bytes"
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// gcmCollision returns a one-block ciphertext with a tag that GCM opens
+// under both k1 and k2 with nonce and no additional data.
+//
+// For the ciphertext block C and the length block L, the tag under a key
+// with H = E(K, 0) and the mask P = E(K, J0) is
+//
+//	T = C*H^2 + L*H + P
+//
+// so both tags are equal if C*(H1^2 + H2^2) = L*(H1 + H2) + P1 + P2.
+func gcmCollision(t *testing.T, k1, k2, nonce []byte) []byte {
+	t.Helper()
+
+	var l [16]byte
+	binary.BigEndian.PutUint64(l[8:], 16*8)
+	length, _ := GF128FromBytes(l[:])
+
+	hp := func(key []byte) (h, p GF128) {
+		var b [16]byte
+		c := NewToyAES(key)
+		c.Encrypt(b[:], b[:])
+		h, _ = GF128FromBytes(b[:])
+		j0 := genCounter(nonce)
+		c.Encrypt(b[:], j0[:])
+		p, _ = GF128FromBytes(b[:])
+		return h, p
+	}
+	h1, p1 := hp(k1)
+	h2, p2 := hp(k2)
+	c := length.Mul(h1.Add(h2)).Add(p1).Add(p2).Mul(h1.Square().Add(h2.Square()).Inverse())
+	tag := c.Mul(h1.Square()).Add(length.Mul(h1)).Add(p1)
+
+	cb, tb := c.Bytes(), tag.Bytes()
+	return append(cb[:], tb[:]...)
+}
+
+func TestKeyCommittingGCM_MultiKeyCollision(t *testing.T) {
+	t.Parallel()
+
+	k1, k2 := make([]byte, 16), make([]byte, 16)
+	_, _ = rand.Read(k1)
+	_, _ = rand.Read(k2)
+	nonce := make([]byte, 12)
+
+	// plain GCM opens the ciphertext under both keys
+	ct := gcmCollision(t, k1, k2, nonce)
+	p1, err1 := NewGCM(NewToyAES(k1)).Open(nil, nonce, ct, nil)
+	p2, err2 := NewGCM(NewToyAES(k2)).Open(nil, nonce, ct, nil)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("collision is not opened by GCM: %v, %v", err1, err2)
+	}
+	if bytes.Equal(p1, p2) {
+		t.Fatal("plaintexts under different keys are equal")
+	}
+
+	c1, _ := NewKeyCommittingGCM(k1)
+	c2, _ := NewKeyCommittingGCM(k2)
+
+	// the same collision without a valid commitment is rejected
+	withCommitment := append(make([]byte, commitmentSize), ct...)
+	for i, aead := range []ccipher.AEAD{c1, c2} {
+		if _, err := aead.Open(nil, nonce, withCommitment, nil); !errors.Is(err, ErrOpen) {
+			t.Errorf("key %d: err = %v, want ErrOpen", i+1, err)
+		}
+	}
+
+	// even a collision of the derived GCM keys prefixed with a valid
+	// commitment for k1 opens only under k1
+	inner := gcmCollision(t, commitEncKey(k1), commitEncKey(k2), nonce)
+	forged := append(c1.(*committingGCM).commitment(nonce), inner...)
+	if _, err := c1.Open(nil, nonce, forged, nil); err != nil {
+		t.Errorf("key 1: %v", err)
+	}
+	if _, err := c2.Open(nil, nonce, forged, nil); !errors.Is(err, ErrOpen) {
+		t.Errorf("key 2: err = %v, want ErrOpen", err)
+	}
+}
+
+func TestKeyCommittingGCM(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{16, 24, 32} {
+		key := make([]byte, n)
+		_, _ = rand.Read(key)
+		aead, err := NewKeyCommittingGCM(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aead.NonceSize() != 12 || aead.Overhead() != 48 {
+			t.Fatalf("NonceSize() = %d, Overhead() = %d, want 12, 48", aead.NonceSize(), aead.Overhead())
+		}
+		nonce := make([]byte, 12)
+		_, _ = rand.Read(nonce)
+		plaintext := []byte("plaintext")
+		ad := []byte("additional data")
+
+		prefix := []byte("prefix")
+		sealed := aead.Seal(prefix, nonce, plaintext, ad)
+		if !bytes.Equal(sealed[:len(prefix)], prefix) {
+			t.Fatalf("Seal does not append to dst: %X", sealed)
+		}
+		sealed = sealed[len(prefix):]
+		got, err := aead.Open(nil, nonce, sealed, ad)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("Open = %X, %v, want %X", got, err, plaintext)
+		}
+
+		for i := range sealed {
+			tampered := bytes.Clone(sealed)
+			tampered[i] ^= 1
+			if _, err := aead.Open(nil, nonce, tampered, ad); !errors.Is(err, ErrOpen) {
+				t.Errorf("ciphertext modified at %d: err = %v, want ErrOpen", i, err)
+			}
+		}
+		other := bytes.Clone(nonce)
+		other[0] ^= 1
+		if _, err := aead.Open(nil, other, sealed, ad); !errors.Is(err, ErrOpen) {
+			t.Errorf("wrong nonce: err = %v, want ErrOpen", err)
+		}
+		if _, err := aead.Open(nil, nonce, sealed[:47], ad); !errors.Is(err, ErrOpen) {
+			t.Errorf("short ciphertext: err = %v, want ErrOpen", err)
+		}
+	}
+
+	if _, err := NewKeyCommittingGCM(make([]byte, 20)); err == nil {
+		t.Error("20-byte key is accepted")
+	}
+}
+
+func TestKeyCommittingGCM_InPlace(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	aead, err := NewKeyCommittingGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	testAEADInPlace(t, aead, nonce)
+
+	// in-place Seal is the same as Seal to new memory
+	plaintext := []byte("plaintext longer than the 32-byte commitment prefix")
+	want := aead.Seal(nil, nonce, plaintext, nil)
+	buf := append(make([]byte, 0, len(want)), plaintext...)
+	if got := aead.Seal(buf[:0], nonce, buf, nil); !bytes.Equal(got, want) {
+		t.Errorf("in-place Seal = %X, want %X", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package toyaes
+
+import (
+	ccipher "crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"sync"
+)
+
+// minChunkBlocks is the least number of blocks given to a worker.
+// Smaller inputs are not worth the goroutines.
+const minChunkBlocks = 64
+
+// ctrEngine XORs src with the keystream E(K, c), E(K, c+1), ...
+//
+// Block i of the keystream depends only on c+i, so the input is split into
+// chunks whose first counter is computed directly, and the chunks are
+// processed by up to workers goroutines. The output is the same as the
+// sequential one.
+type ctrEngine struct {
+	block ccipher.Block
+	// workers <= 1 means sequential
+	workers int
+	// width is the number of the last bytes of the counter block that are
+	// incremented: 4 for GCM (inc32), 16 for CTR mode
+	width int
+}
+
+// addCounter returns c + n, where only the last width bytes are incremented
+// and wrap around
+func addCounter(c [size]byte, n uint64, width int) [size]byte {
+	if width == 4 {
+		x := c[size-4:]
+		binary.BigEndian.PutUint32(x, binary.BigEndian.Uint32(x)+uint32(n))
+		return c
+	}
+	lo := binary.BigEndian.Uint64(c[8:])
+	sum := lo + n
+	binary.BigEndian.PutUint64(c[8:], sum)
+	if sum < lo {
+		binary.BigEndian.PutUint64(c[:8], binary.BigEndian.Uint64(c[:8])+1)
+	}
+	return c
+}
+
+// xorKeyStream XORs src into dst starting with counter c.
+// dst must be at least as long as src.
+func (e *ctrEngine) xorKeyStream(dst, src []byte, c [size]byte) {
+	blocks := (len(src) + size - 1) / size
+	workers := min(e.workers, blocks/minChunkBlocks)
+	if workers <= 1 {
+		e.xorKeyStreamSeq(dst, src, c)
+		return
+	}
+
+	chunk := (blocks + workers - 1) / workers * size
+	var wg sync.WaitGroup
+	for start := 0; start < len(src); start += chunk {
+		end := min(start+chunk, len(src))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			e.xorKeyStreamSeq(dst[start:end], src[start:end], addCounter(c, uint64(start/size), e.width))
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+func (e *ctrEngine) xorKeyStreamSeq(dst, src []byte, c [size]byte) {
+	var mask [size]byte
+	for len(src) > 0 {
+		e.block.Encrypt(mask[:], c[:])
+		// the last block may be shorter than `size`
+		n := subtle.XORBytes(dst, src, mask[:])
+		dst, src = dst[n:], src[n:]
+		c = addCounter(c, 1, e.width)
+	}
+}
+
+// NewCTR returns a cipher.Stream of CTR mode (SP 800-38A section 6.5).
+// The whole iv is the initial counter block and is incremented as a
+// 128-bit big-endian integer.
+func NewCTR(block ccipher.Block, iv []byte) ccipher.Stream {
+	return NewParallelCTR(block, iv, 1)
+}
+
+// NewParallelCTR is NewCTR which generates the keystream of a large
+// XORKeyStream call by up to workers goroutines
+func NewParallelCTR(block ccipher.Block, iv []byte, workers int) ccipher.Stream {
+	if block.BlockSize() != size {
+		panic("toyaes: CTR requires 128-bit block cipher")
+	}
+	if len(iv) != size {
+		panic("toyaes: IV length must equal block size")
+	}
+	s := &ctr{
+		engine: ctrEngine{block: block, workers: workers, width: size},
+		used:   size,
+	}
+	copy(s.counter[:], iv)
+	return s
+}
+
+type ctr struct {
+	engine  ctrEngine
+	counter [size]byte
+	// ks[used:] is the keystream left from the previous call
+	ks   [size]byte
+	used int
+}
+
+// XORKeyStream implements cipher.Stream
+func (s *ctr) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("toyaes: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("toyaes: invalid buffer overlap")
+	}
+	if s.used < size {
+		n := subtle.XORBytes(dst, src, s.ks[s.used:])
+		s.used += n
+		dst, src = dst[n:], src[n:]
+	}
+	if full := len(src) / size * size; full > 0 {
+		s.engine.xorKeyStream(dst, src[:full], s.counter)
+		s.counter = addCounter(s.counter, uint64(full/size), size)
+		dst, src = dst[full:], src[full:]
+	}
+	if len(src) > 0 {
+		s.engine.block.Encrypt(s.ks[:], s.counter[:])
+		s.counter = addCounter(s.counter, 1, size)
+		s.used = subtle.XORBytes(dst, src, s.ks[:])
+	}
+}
@@ -0,0 +1,140 @@
+package toyaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_addCounter(t *testing.T) {
+	t.Parallel()
+
+	c := [16]byte{15: 0xff}
+	c[11] = 0xaa
+	c[12], c[13], c[14] = 0xff, 0xff, 0xff
+	if got, want := addCounter(c, 1, 4), [16]byte{11: 0xaa}; got != want {
+		t.Errorf("32-bit wrap: got=%X, want=%X", got, want)
+	}
+	if got, want := addCounter(c, 1, 16), [16]byte{10: 0, 11: 0xab}; got != want {
+		t.Errorf("128-bit carry: got=%X, want=%X", got, want)
+	}
+	all := [16]byte{}
+	for i := range all {
+		all[i] = 0xff
+	}
+	if got := addCounter(all, 2, 16); got != [16]byte{15: 0x01} {
+		t.Errorf("128-bit wrap: got=%X", got)
+	}
+}
+
+func Test_ctrEngine_parallel(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	block := NewToyAES(key, WithTTables())
+
+	for _, n := range []int{0, 1, 16 * minChunkBlocks, 16*minChunkBlocks*4 + 5, 100000} {
+		src := make([]byte, n)
+		_, _ = rand.Read(src)
+		for _, width := range []int{4, 16} {
+			var c [16]byte
+			_, _ = rand.Read(c[:])
+			// start near the wrap of the counter
+			for i := size - width; i < size-1; i++ {
+				c[i] = 0xff
+			}
+
+			want := make([]byte, n)
+			(&ctrEngine{block: block, workers: 1, width: width}).xorKeyStream(want, src, c)
+			for _, workers := range []int{2, 3, 8} {
+				got := make([]byte, n)
+				(&ctrEngine{block: block, workers: workers, width: width}).xorKeyStream(got, src, c)
+				if !bytes.Equal(got, want) {
+					t.Fatalf("len=%d, width=%d, workers=%d: differs from sequential", n, width, workers)
+				}
+			}
+		}
+	}
+}
+
+func TestCTR(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	iv := make([]byte, 16)
+	src := make([]byte, 50000)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(src)
+	// the counter carries into the upper 64 bits
+	_, _ = rand.Read(iv[:8])
+	for i := 8; i < 16; i++ {
+		iv[i] = 0xff
+	}
+
+	gob, _ := aes.NewCipher(key)
+	want := make([]byte, len(src))
+	ccipher.NewCTR(gob, iv).XORKeyStream(want, src)
+
+	for _, workers := range []int{1, 4} {
+		s := NewParallelCTR(NewToyAES(key, WithTTables()), iv, workers)
+		got := make([]byte, len(src))
+		// uneven calls keep the leftover keystream
+		for off, step := 0, 1; off < len(src); off, step = off+step, step*3+1 {
+			end := min(off+step, len(src))
+			s.XORKeyStream(got[off:end], src[off:end])
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("workers=%d: differs from crypto/cipher", workers)
+		}
+	}
+}
+
+func TestSeal_CTRWorkers(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	nonce := make([]byte, 12)
+	plaintext := make([]byte, 70000)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(nonce)
+	_, _ = rand.Read(plaintext)
+
+	aead := NewGCM(NewToyAES(key, WithTTables()), WithCTRWorkers(4))
+	got := aead.Seal(nil, nonce, plaintext, nil)
+
+	aesb, _ := aes.NewCipher(key)
+	aeadgo, _ := ccipher.NewGCM(aesb)
+	want := aeadgo.Seal(nil, nonce, plaintext, nil)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("differs from crypto/cipher")
+	}
+	pt, err := aead.Open(nil, nonce, got, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, plaintext) {
+		t.Fatal("Open returns another plaintext")
+	}
+}
+
+func BenchmarkCTR(b *testing.B) {
+	key := make([]byte, 16)
+	iv := make([]byte, 16)
+	src := make([]byte, 1<<20)
+	dst := make([]byte, len(src))
+	block := NewToyAES(key, WithTTables())
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			for n := 0; n < b.N; n++ {
+				NewParallelCTR(block, iv, workers).XORKeyStream(dst, src)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package toyaes
+
+import (
+	"errors"
+	"strconv"
+)
+
+// KeySizeError is returned for a key whose length is not 16, 24 or 32 bytes
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "toyaes: invalid key size " + strconv.Itoa(int(k))
+}
+
+// NonceSizeError is returned by Open for a nonce whose length differs from NonceSize
+type NonceSizeError int
+
+func (n NonceSizeError) Error() string {
+	return "toyaes: invalid nonce size " + strconv.Itoa(int(n))
+}
+
+// TagSizeError is returned by NewGCMWithTagSize for an unsupported tag size
+type TagSizeError int
+
+func (t TagSizeError) Error() string {
+	return "toyaes: invalid tag size " + strconv.Itoa(int(t))
+}
+
+// ErrOpen is returned by Open when the ciphertext is too short or
+// is not authenticated by the tag
+var ErrOpen = errors.New("toyaes: message authentication failed")
+
+// errBlockSize is returned by NewGCMWithError for a cipher whose block size is not 16 bytes
+var errBlockSize = errors.New("toyaes: NewGCM requires 128-bit block cipher")
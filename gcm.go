@@ -4,11 +4,20 @@ import (
 	ccipher "crypto/cipher"
 	"crypto/subtle"
 	"encoding/binary"
-	"errors"
 )
 
 const size = 16
 
+// gcmStandardNonceSize is the nonce size of NewGCM, which needs no GHASH for J0
+const gcmStandardNonceSize = 12
+
+const (
+	// gcmTagSize is the tag size of NewGCM
+	gcmTagSize = 16
+	// gcmMinTagSize is the minimum tag size without WithShortTags
+	gcmMinTagSize = 12
+)
+
 func genCounter(nonce []byte) [size]byte {
 	var counter [size]byte
 	copy(counter[:], nonce)
@@ -58,33 +67,13 @@ func rightShift(u uint128) uint128 {
 	}
 }
 
-func split(in []byte) <-chan uint128 {
-	ch := make(chan uint128)
-	go func() {
-		defer close(ch)
-		for i := 0; i < len(in); i += 16 {
-			var out [16]byte
-			copy(out[:], in[i:])
-			ch <- newUint128(out[:])
-		}
-	}()
-	return ch
-}
-
-func ghash(cipherText, additionalData, hk []byte) [16]byte {
-	h := newUint128(hk)
-	var x uint128
-	for v := range split(additionalData) {
-		x = mulg(add(x, v), h)
-	}
-	for v := range split(cipherText) {
-		x = mulg(add(x, v), h)
-	}
-
-	x = mulg(add(x, uint128{
+func ghash(key *ghashKey, cipherText, additionalData []byte) [16]byte {
+	x := key.update(uint128{}, additionalData)
+	x = key.update(x, cipherText)
+	x = key.pow[0].mulH(add(x, uint128{
 		uint64(len(additionalData)) * 8,
 		uint64(len(cipherText)) * 8,
-	}), h)
+	}))
 
 	var hashed [16]byte
 	pubUint128(hashed[:], x)
@@ -93,65 +82,204 @@ func ghash(cipherText, additionalData, hk []byte) [16]byte {
 
 var _ ccipher.AEAD = (*toyGCM)(nil)
 
-func NewGCM(cipher ccipher.Block) ccipher.AEAD {
-	return &toyGCM{cipher: cipher}
+// GCMOption configures NewGCM
+type GCMOption func(*gcmConfig)
+
+type gcmConfig struct {
+	ghash       GHASHMethod
+	aggregation int
+	workers     int
+	shortTags   bool
+	usage       *UsageTracker
+}
+
+// WithGHASH selects the implementation of GHASH. The default is GHASHShoup4.
+func WithGHASH(method GHASHMethod) GCMOption {
+	return func(c *gcmConfig) { c.ghash = method }
+}
+
+// WithGHASHAggregation makes GHASH process k blocks at once with
+// precomputed H^1..H^k:
+//
+//	Y = (Y + X1)*H^k + X2*H^(k-1) + ... + Xk*H
+//
+// The k multiplications are independent of each other, unlike the
+// sequential Y = (Y + X)*H. k <= 1 disables aggregation.
+func WithGHASHAggregation(k int) GCMOption {
+	return func(c *gcmConfig) { c.aggregation = k }
+}
+
+// WithCTRWorkers makes Seal and Open generate the keystream of large
+// messages by up to n goroutines. The default is 1, which is sequential.
+func WithCTRWorkers(n int) GCMOption {
+	return func(c *gcmConfig) { c.workers = n }
+}
+
+// WithShortTags allows NewGCMWithTagSize to use 4 and 8-byte tags.
+// SP 800-38D Appendix C limits the length of messages and the number of
+// failed Open calls per key for them, which this package does not enforce.
+func WithShortTags() GCMOption {
+	return func(c *gcmConfig) { c.shortTags = true }
+}
+
+// NewGCM returns GCM with a 12-byte nonce and a 16-byte tag.
+// It panics if the block size of cipher is not 16 bytes;
+// NewGCMWithError returns an error instead.
+func NewGCM(cipher ccipher.Block, opts ...GCMOption) ccipher.AEAD {
+	g, err := NewGCMWithError(cipher, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewGCMWithError is NewGCM which returns an error for a cipher whose
+// block size is not 16 bytes
+func NewGCMWithError(cipher ccipher.Block, opts ...GCMOption) (ccipher.AEAD, error) {
+	return newGCM(cipher, gcmStandardNonceSize, gcmTagSize, opts...)
+}
+
+// NewGCMWithNonceSize returns GCM with a nonce of nonceSize bytes.
+// A nonce other than 12 bytes is hashed by GHASH into the pre-counter block J0
+// (SP 800-38D section 7.1); use it only for compatibility with existing systems.
+func NewGCMWithNonceSize(cipher ccipher.Block, nonceSize int, opts ...GCMOption) (ccipher.AEAD, error) {
+	if nonceSize <= 0 {
+		return nil, NonceSizeError(nonceSize)
+	}
+	return newGCM(cipher, nonceSize, gcmTagSize, opts...)
+}
+
+// NewGCMWithTagSize returns GCM which truncates the tag to tagSize bytes.
+// tagSize must be between 12 and 16, or 4 or 8 with WithShortTags.
+func NewGCMWithTagSize(cipher ccipher.Block, tagSize int, opts ...GCMOption) (ccipher.AEAD, error) {
+	return newGCM(cipher, gcmStandardNonceSize, tagSize, opts...)
+}
+
+func newGCM(cipher ccipher.Block, nonceSize, tagSize int, opts ...GCMOption) (ccipher.AEAD, error) {
+	if cipher.BlockSize() != size {
+		return nil, errBlockSize
+	}
+	var conf gcmConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	switch {
+	case gcmMinTagSize <= tagSize && tagSize <= gcmTagSize:
+	case conf.shortTags && (tagSize == 4 || tagSize == 8):
+	default:
+		return nil, TagSizeError(tagSize)
+	}
+
+	// H is fixed for the key, so the table for it is made only once
+	var hk [size]byte
+	cipher.Encrypt(hk[:], hk[:])
+	return &toyGCM{
+		cipher:    cipher,
+		nonceSize: nonceSize,
+		tagSize:   tagSize,
+		usage:     conf.usage,
+		ghash:     newGHASHKey(conf.ghash, newUint128(hk[:]), conf.aggregation),
+		// GCM increments only the last 32 bits of the counter block
+		ctr: ctrEngine{block: cipher, workers: conf.workers, width: 4},
+	}, nil
 }
 
 type toyGCM struct {
-	cipher ccipher.Block
+	cipher    ccipher.Block
+	nonceSize int
+	tagSize   int
+	usage     *UsageTracker
+	ghash     *ghashKey
+	ctr       ctrEngine
 }
 
 // NonceSize implements cipher.AEAD
-func (*toyGCM) NonceSize() int { return 12 }
+func (ta *toyGCM) NonceSize() int { return ta.nonceSize }
 
 // Overhead implements cipher.AEAD
-func (*toyGCM) Overhead() int { return 16 }
+func (ta *toyGCM) Overhead() int { return ta.tagSize }
 
 // Open implements cipher.AEAD
 func (ta *toyGCM) Open(dst []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
-	tags := ciphertext[len(ciphertext)-16:]
-	ciphertext = ciphertext[:len(ciphertext)-16]
-
-	hk := make([]byte, 16)
-	ta.cipher.Encrypt(hk, make([]byte, 16))
-
-	hash := ghash(ciphertext, additionalData, hk)
-
-	encryptedCounter := make([]byte, 16)
-	c := genCounter(nonce)
-	ta.cipher.Encrypt(encryptedCounter, c[:])
+	ret, err := ta.open(dst, nonce, ciphertext, additionalData)
+	if ta.usage != nil {
+		ta.usage.open(len(ret)-len(dst), err == nil)
+	}
+	return ret, err
+}
 
-	expectedTags := make([]byte, len(encryptedCounter[:]))
-	subtle.XORBytes(expectedTags, encryptedCounter[:], hash[:])
+func (ta *toyGCM) open(dst []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(nonce) != ta.NonceSize() {
+		return nil, NonceSizeError(len(nonce))
+	}
+	if len(ciphertext) < ta.Overhead() || uint64(len(ciphertext)-ta.Overhead()) > gcmMaxPlaintext {
+		return nil, ErrOpen
+	}
+	tags := ciphertext[len(ciphertext)-ta.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-ta.tagSize]
 
-	if subtle.ConstantTimeCompare(expectedTags, tags) != 1 {
-		return nil, errors.New("invalid tags")
+	expectedTags := ta.tag(nonce, ciphertext, additionalData)
+	if subtle.ConstantTimeCompare(expectedTags[:ta.tagSize], tags) != 1 {
+		return nil, ErrOpen
 	}
 
-	counter := incrementCounter(genCounter(nonce))
-	ct := ta.encWitchCounter(ciphertext, nonce, counter)
-	return ct, nil
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	if inexactOverlap(out, ciphertext) {
+		panic("toyaes: invalid buffer overlap")
+	}
+	counter := incrementCounter(ta.j0(nonce))
+	ta.ctr.xorKeyStream(out, ciphertext, counter)
+	return ret, nil
 }
 
 // Seal implements cipher.AEAD
 func (ta *toyGCM) Seal(dst []byte, nonce []byte, plaintext []byte, additionalData []byte) []byte {
-	counter := incrementCounter(genCounter(nonce))
-	ct := ta.encWitchCounter(plaintext, nonce, counter)
+	if len(nonce) != ta.NonceSize() {
+		panic("toyaes: incorrect nonce length given to GCM")
+	}
+	if uint64(len(plaintext)) > gcmMaxPlaintext {
+		panic("toyaes: message too large for GCM")
+	}
+	if ta.usage != nil {
+		if err := ta.usage.seal(len(plaintext)); err != nil {
+			panic(err)
+		}
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+ta.Overhead())
+	if inexactOverlap(out, plaintext) {
+		panic("toyaes: invalid buffer overlap")
+	}
+	ct, tags := out[:len(plaintext)], out[len(plaintext):]
 
-	hk := make([]byte, 16)
-	ta.cipher.Encrypt(hk, make([]byte, 16))
+	counter := incrementCounter(ta.j0(nonce))
+	ta.ctr.xorKeyStream(ct, plaintext, counter)
 
-	hash := ghash(ct, additionalData, hk)
+	t := ta.tag(nonce, ct, additionalData)
+	copy(tags, t[:])
+	return ret
+}
 
-	encryptedCounter := make([]byte, 16)
-	c := genCounter(nonce)
-	ta.cipher.Encrypt(encryptedCounter, c[:])
+// j0 returns the pre-counter block J0. A 12-byte nonce is followed by the
+// 32-bit counter 1; any other nonce is GHASH(nonce || 0^s || [0]64 || [len(nonce)]64),
+// which is ghash with the nonce as the ciphertext and no additional data.
+func (ta *toyGCM) j0(nonce []byte) [size]byte {
+	if len(nonce) == gcmStandardNonceSize {
+		return genCounter(nonce)
+	}
+	return ghash(ta.ghash, nonce, nil)
+}
 
-	tags := make([]byte, len(encryptedCounter[:]))
-	subtle.XORBytes(tags, encryptedCounter[:], hash[:])
+// tag returns GHASH(ciphertext, additionalData) xor E(K, J0)
+func (ta *toyGCM) tag(nonce, ciphertext, additionalData []byte) [16]byte {
+	hash := ghash(ta.ghash, ciphertext, additionalData)
 
-	ct = append(ct, tags[:]...)
-	return ct
+	var encryptedCounter [16]byte
+	c := ta.j0(nonce)
+	ta.cipher.Encrypt(encryptedCounter[:], c[:])
+
+	var tags [16]byte
+	subtle.XORBytes(tags[:], encryptedCounter[:], hash[:])
+	return tags
 }
 
 func (ta *toyGCM) enc(plaintext, nonce []byte) []byte {
@@ -159,29 +287,8 @@ func (ta *toyGCM) enc(plaintext, nonce []byte) []byte {
 }
 
 func (ta *toyGCM) encWitchCounter(plaintext, _ []byte, c [16]byte) []byte {
-	blockNum, r := len(plaintext)/size, len(plaintext)%size
-	if r != 0 {
-		blockNum++
-	}
-	// plaintext は `size` の倍数であるとは限らないため、
-	// plaintext より大きい倍数になるものを暗号化対象にする
-	ct := make([]byte, blockNum*size)
-	copy(ct, plaintext)
-
-	for i := 0; i < blockNum; i++ {
-		start, end := size*i, size*(i+1)
-		pt := ct[start:end]
-
-		var mask [size]byte
-		ta.cipher.Encrypt(mask[:], c[:])
-
-		out := make([]byte, len(pt))
-		subtle.XORBytes(out, pt, mask[:])
-		copy(ct[start:end], out)
-
-		c = incrementCounter(c)
-	}
-	// 事前にもともとの `plaintext`より大きいサイズになっている可能性があるので、削る
 	// 暗号化前後でバイト列の長さは変わらない
-	return ct[:len(plaintext)]
+	ct := make([]byte, len(plaintext))
+	ta.ctr.xorKeyStream(ct, plaintext, c)
+	return ct
 }
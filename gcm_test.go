@@ -1,10 +1,12 @@
 package toyaes
 
 import (
+	"bytes"
 	"crypto/aes"
 	ccipher "crypto/cipher"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -378,3 +380,384 @@ func TestOpenUsingGoAES(t *testing.T) {
 		}
 	}
 }
+
+// The following tests follow the AEAD conformance tests of the standard library.
+
+func newTestAEAD(t *testing.T) ccipher.AEAD {
+	t.Helper()
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	return NewGCM(NewToyAES(key))
+}
+
+func TestAEAD_AppendDst(t *testing.T) {
+	t.Parallel()
+
+	aead := newTestAEAD(t)
+	nonce := make([]byte, aead.NonceSize())
+	plaintext := []byte("plaintext appended after the prefix")
+	ad := []byte("additional data")
+	prefix := []byte("prefix")
+
+	sealed := aead.Seal(nil, nonce, plaintext, ad)
+	for _, c := range []int{len(prefix), len(prefix) + len(sealed), 1000} {
+		dst := make([]byte, len(prefix), c)
+		copy(dst, prefix)
+		got := aead.Seal(dst, nonce, plaintext, ad)
+		if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], sealed) {
+			t.Fatalf("cap=%d: Seal(prefix) = %X, want prefix followed by %X", c, got, sealed)
+		}
+
+		dst = make([]byte, len(prefix), c)
+		copy(dst, prefix)
+		got, err := aead.Open(dst, nonce, sealed, ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], plaintext) {
+			t.Fatalf("cap=%d: Open(prefix) = %X, want prefix followed by %X", c, got, plaintext)
+		}
+	}
+}
+
+func TestAEAD_InPlace(t *testing.T) {
+	t.Parallel()
+
+	aead := newTestAEAD(t)
+	nonce := make([]byte, aead.NonceSize())
+	plaintext := make([]byte, 100)
+	_, _ = rand.Read(plaintext)
+	want := aead.Seal(nil, nonce, plaintext, nil)
+
+	buf := make([]byte, len(plaintext), len(plaintext)+aead.Overhead())
+	copy(buf, plaintext)
+	sealed := aead.Seal(buf[:0], nonce, buf, nil)
+	if !bytes.Equal(sealed, want) {
+		t.Fatalf("in-place Seal = %X, want %X", sealed, want)
+	}
+	if &sealed[0] != &buf[0] {
+		t.Error("in-place Seal does not reuse dst")
+	}
+
+	opened, err := aead.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("in-place Open = %X, want %X", opened, plaintext)
+	}
+}
+
+func TestAEAD_InexactOverlap(t *testing.T) {
+	t.Parallel()
+
+	aead := newTestAEAD(t)
+	nonce := make([]byte, aead.NonceSize())
+	buf := make([]byte, 100)
+	sealed := aead.Seal(nil, nonce, buf[:50], nil)
+	copy(buf, sealed)
+
+	mustPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s does not panic on inexact overlap", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("Seal", func() { aead.Seal(buf[1:1], nonce, buf[:50], nil) })
+	mustPanic("Open", func() { _, _ = aead.Open(buf[1:1], nonce, buf[:len(sealed)], nil) })
+}
+
+func TestAEAD_InputNotModified(t *testing.T) {
+	t.Parallel()
+
+	aead := newTestAEAD(t)
+	nonce := make([]byte, aead.NonceSize())
+	plaintext := []byte("plaintext")
+	ad := []byte("additional data")
+	_, _ = rand.Read(nonce)
+	n, p, a := bytes.Clone(nonce), bytes.Clone(plaintext), bytes.Clone(ad)
+
+	sealed := aead.Seal(nil, nonce, plaintext, ad)
+	s := bytes.Clone(sealed)
+	if _, err := aead.Open(nil, nonce, sealed, ad); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(n, nonce) || !bytes.Equal(p, plaintext) || !bytes.Equal(a, ad) || !bytes.Equal(s, sealed) {
+		t.Error("input is modified")
+	}
+}
+
+func TestAEAD_Reject(t *testing.T) {
+	t.Parallel()
+
+	aead := newTestAEAD(t)
+	nonce := make([]byte, aead.NonceSize())
+	ad := []byte("additional data")
+	sealed := aead.Seal(nil, nonce, []byte("plaintext"), ad)
+
+	otherNonce := bytes.Clone(nonce)
+	otherNonce[0] ^= 1
+	if _, err := aead.Open(nil, otherNonce, sealed, ad); err == nil {
+		t.Error("wrong nonce is accepted")
+	}
+	if _, err := aead.Open(nil, nonce, sealed, []byte("other")); err == nil {
+		t.Error("wrong additional data is accepted")
+	}
+	for i := range sealed {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 0x80
+		if _, err := aead.Open(nil, nonce, tampered, ad); err == nil {
+			t.Errorf("ciphertext modified at %d is accepted", i)
+		}
+	}
+}
+
+func TestOpen_Malformed(t *testing.T) {
+	t.Parallel()
+
+	aead := newTestAEAD(t)
+	nonce := make([]byte, aead.NonceSize())
+	sealed := aead.Seal(nil, nonce, []byte("plaintext"), nil)
+
+	for _, n := range []int{0, 8, 11, 13, 16} {
+		_, err := aead.Open(nil, make([]byte, n), sealed, nil)
+		var ne NonceSizeError
+		if !errors.As(err, &ne) || int(ne) != n {
+			t.Errorf("nonce size %d: err = %v, want NonceSizeError(%d)", n, err, n)
+		}
+	}
+	for _, n := range []int{0, 1, 15} {
+		if _, err := aead.Open(nil, nonce, sealed[:n], nil); !errors.Is(err, ErrOpen) {
+			t.Errorf("ciphertext size %d: err = %v, want ErrOpen", n, err)
+		}
+	}
+	sealed[0] ^= 1
+	if _, err := aead.Open(nil, nonce, sealed, nil); !errors.Is(err, ErrOpen) {
+		t.Errorf("modified ciphertext: err = %v, want ErrOpen", err)
+	}
+}
+
+func TestNewGCMWithError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewGCMWithError(NewToyAES(make([]byte, 16))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGCMWithError(fakeBlock{8}); err == nil {
+		t.Error("8-byte block cipher is accepted")
+	}
+}
+
+type fakeBlock struct{ size int }
+
+func (b fakeBlock) BlockSize() int          { return b.size }
+func (b fakeBlock) Encrypt(dst, src []byte) { copy(dst, src) }
+func (b fakeBlock) Decrypt(dst, src []byte) { copy(dst, src) }
+
+func FuzzOpen(f *testing.F) {
+	key := make([]byte, 16)
+	aead := NewGCM(NewToyAES(key))
+	block, _ := aes.NewCipher(key)
+	want, _ := ccipher.NewGCM(block)
+
+	nonce := make([]byte, 12)
+	f.Add(nonce, aead.Seal(nil, nonce, []byte("plaintext"), []byte("ad")), []byte("ad"))
+	f.Add(nonce, []byte{}, []byte{})
+	f.Add([]byte{0}, make([]byte, 15), []byte{})
+	f.Fuzz(func(t *testing.T, nonce, ciphertext, additionalData []byte) {
+		got, err := aead.Open(nil, nonce, ciphertext, additionalData)
+		if len(nonce) != aead.NonceSize() {
+			if _, ok := err.(NonceSizeError); !ok {
+				t.Fatalf("err = %v, want NonceSizeError", err)
+			}
+			return
+		}
+		if err != nil && !errors.Is(err, ErrOpen) {
+			t.Fatalf("err = %v, want ErrOpen", err)
+		}
+		exp, expErr := want.Open(nil, nonce, ciphertext, additionalData)
+		if (err == nil) != (expErr == nil) || !bytes.Equal(got, exp) {
+			t.Fatalf("Open = %X, %v, want %X, %v", got, err, exp, expErr)
+		}
+	})
+}
+
+func FuzzSealOpen(f *testing.F) {
+	aead := NewGCM(NewToyAES(make([]byte, 16)))
+	f.Add(make([]byte, 12), []byte("plaintext"), []byte("ad"))
+	f.Fuzz(func(t *testing.T, nonce, plaintext, additionalData []byte) {
+		if len(nonce) != aead.NonceSize() {
+			return
+		}
+		sealed := aead.Seal(nil, nonce, plaintext, additionalData)
+		got, err := aead.Open(nil, nonce, sealed, additionalData)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("Open(Seal(%X)) = %X, %v", plaintext, got, err)
+		}
+	})
+}
+
+func TestNewGCMWithNonceSize(t *testing.T) {
+	t.Parallel()
+
+	// Test Cases 5 and 6 of McGrew and Viega, "The Galois/Counter Mode of Operation (GCM)"
+	const (
+		key       = "feffe9928665731c6d6a8f9467308308"
+		plaintext = "d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39"
+		ad        = "feedfacedeadbeeffeedfacedeadbeefabaddad2"
+	)
+	tests := []struct {
+		name       string
+		nonce      string
+		ciphertext string
+	}{
+		{
+			name:       "test case 5: 8-byte IV",
+			nonce:      "cafebabefacedbad",
+			ciphertext: "61353b4c2806934a777ff51fa22a4755699b2a714fcdc6f83766e5f97b6c742373806900e49f24b22b097544d4896b424989b5e1ebac0f07c23f4598" + "3612d2e79e3b0785561be14aaca2fccb",
+		},
+		{
+			name:       "test case 6: 60-byte IV",
+			nonce:      "9313225df88406e555909c5aff5269aa6a7a9538534f7da1e4c303d2a318a728c3c0c95156809539fcf0e2429a6b525416aedbf5a0de6a57a637b39b",
+			ciphertext: "8ce24998625615b603a033aca13fb894be9112a5c3a211a8ba262a3cca7e2ca701e4a9a4fba43c90ccdcb281d48c7c6fd62875d2aca417034c34aee5" + "619cc5aefffe0bfa462af43c1699d050",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			k, _ := hex.DecodeString(key)
+			nonce, _ := hex.DecodeString(tt.nonce)
+			p, _ := hex.DecodeString(plaintext)
+			a, _ := hex.DecodeString(ad)
+			want, _ := hex.DecodeString(tt.ciphertext)
+
+			for _, method := range []GHASHMethod{GHASHShoup4, GHASHShoup8, GHASHBitwise} {
+				aead, err := NewGCMWithNonceSize(NewToyAES(k), len(nonce), WithGHASH(method))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if aead.NonceSize() != len(nonce) {
+					t.Fatalf("NonceSize() = %d, want %d", aead.NonceSize(), len(nonce))
+				}
+				got := aead.Seal(nil, nonce, p, a)
+				if !bytes.Equal(got, want) {
+					t.Fatalf("method %v: Seal = %x, want %x", method, got, want)
+				}
+				opened, err := aead.Open(nil, nonce, got, a)
+				if err != nil || !bytes.Equal(opened, p) {
+					t.Fatalf("method %v: Open = %x, %v, want %x", method, opened, err, p)
+				}
+			}
+		})
+	}
+}
+
+func TestNewGCMWithNonceSize_Go(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	block, _ := aes.NewCipher(key)
+	plaintext := make([]byte, 100)
+	_, _ = rand.Read(plaintext)
+	ad := []byte("additional data")
+
+	for _, n := range []int{1, 8, 12, 15, 16, 17, 32, 60, 128} {
+		want, err := ccipher.NewGCMWithNonceSize(block, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aead, err := NewGCMWithNonceSize(NewToyAES(key), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := make([]byte, n)
+		_, _ = rand.Read(nonce)
+		got := aead.Seal(nil, nonce, plaintext, ad)
+		if exp := want.Seal(nil, nonce, plaintext, ad); !bytes.Equal(got, exp) {
+			t.Errorf("nonce size %d: Seal = %X, want %X", n, got, exp)
+		}
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := NewGCMWithNonceSize(NewToyAES(key), n); err == nil {
+			t.Errorf("nonce size %d is accepted", n)
+		}
+	}
+}
+
+func TestNewGCMWithTagSize(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	block, _ := aes.NewCipher(key)
+	nonce := make([]byte, 12)
+	_, _ = rand.Read(nonce)
+	plaintext := []byte("plaintext with a truncated tag")
+	ad := []byte("additional data")
+
+	for _, n := range []int{12, 13, 14, 15, 16} {
+		want, err := ccipher.NewGCMWithTagSize(block, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aead, err := NewGCMWithTagSize(NewToyAES(key), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aead.Overhead() != n {
+			t.Errorf("Overhead() = %d, want %d", aead.Overhead(), n)
+		}
+		got := aead.Seal(nil, nonce, plaintext, ad)
+		if exp := want.Seal(nil, nonce, plaintext, ad); !bytes.Equal(got, exp) {
+			t.Errorf("tag size %d: Seal = %X, want %X", n, got, exp)
+		}
+		if _, err := aead.Open(nil, nonce, got, ad); err != nil {
+			t.Errorf("tag size %d: %v", n, err)
+		}
+		got[len(got)-1] ^= 1
+		if _, err := aead.Open(nil, nonce, got, ad); !errors.Is(err, ErrOpen) {
+			t.Errorf("tag size %d: err = %v for a modified tag, want ErrOpen", n, err)
+		}
+	}
+}
+
+func TestNewGCMWithTagSize_Short(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	nonce := make([]byte, 12)
+	plaintext := []byte("plaintext")
+	full := NewGCM(NewToyAES(key)).Seal(nil, nonce, plaintext, nil)
+
+	for _, n := range []int{4, 8} {
+		if _, err := NewGCMWithTagSize(NewToyAES(key), n); err == nil {
+			t.Errorf("tag size %d is accepted without WithShortTags", n)
+		}
+		aead, err := NewGCMWithTagSize(NewToyAES(key), n, WithShortTags())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the tag is the leftmost bytes of the full tag
+		got := aead.Seal(nil, nonce, plaintext, nil)
+		if want := full[:len(plaintext)+n]; !bytes.Equal(got, want) {
+			t.Errorf("tag size %d: Seal = %X, want %X", n, got, want)
+		}
+		if _, err := aead.Open(nil, nonce, got, nil); err != nil {
+			t.Errorf("tag size %d: %v", n, err)
+		}
+	}
+
+	for _, n := range []int{-1, 0, 3, 5, 6, 7, 9, 10, 11, 17, 32} {
+		_, err := NewGCMWithTagSize(NewToyAES(key), n, WithShortTags())
+		var te TagSizeError
+		if !errors.As(err, &te) || int(te) != n {
+			t.Errorf("tag size %d: err = %v, want TagSizeError(%d)", n, err, n)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package toyaes
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"slices"
+)
+
+// GF128 is an element of GF(2^128) in the bit order of GCM.
+//
+// The field is defined by x^128 + x^7 + x^2 + x + 1 and the most significant
+// bit of the first byte is the coefficient of x^0 (SP 800-38D section 6.3).
+// The zero value is 0.
+type GF128 struct {
+	v uint128
+}
+
+// GF128FromBytes returns the element represented by 16 bytes in GCM order
+func GF128FromBytes(b []byte) (GF128, error) {
+	if len(b) != 16 {
+		return GF128{}, errors.New("toyaes: GF128 requires 16 bytes")
+	}
+	return GF128{newUint128(b)}, nil
+}
+
+// GF128FromBig returns the element whose coefficient of x^i is bit i of n.
+// n must satisfy 0 <= n < 2^128.
+func GF128FromBig(n *big.Int) (GF128, error) {
+	b, err := bigTo16(n)
+	if err != nil {
+		return GF128{}, err
+	}
+	return GF128{newUint128(b[:]).reverse()}, nil
+}
+
+// GF128One returns 1
+func GF128One() GF128 {
+	return GF128{uint128{1 << 63, 0}}
+}
+
+// Bytes returns the 16 bytes representation in GCM order
+func (a GF128) Bytes() [16]byte {
+	var b [16]byte
+	pubUint128(b[:], a.v)
+	return b
+}
+
+// Big returns the integer whose bit i is the coefficient of x^i
+func (a GF128) Big() *big.Int {
+	var b [16]byte
+	pubUint128(b[:], a.v.reverse())
+	return new(big.Int).SetBytes(b[:])
+}
+
+// String returns Bytes in hex
+func (a GF128) String() string {
+	b := a.Bytes()
+	return hex.EncodeToString(b[:])
+}
+
+// IsZero reports whether a is 0
+func (a GF128) IsZero() bool { return a.v == uint128{} }
+
+// Add returns a + b
+func (a GF128) Add(b GF128) GF128 { return GF128{add(a.v, b.v)} }
+
+// Mul returns a * b
+func (a GF128) Mul(b GF128) GF128 { return GF128{mulg(a.v, b.v)} }
+
+// Square returns a * a
+func (a GF128) Square() GF128 { return a.Mul(a) }
+
+// Exp returns a^e. If e is negative, a must not be 0.
+func (a GF128) Exp(e *big.Int) GF128 {
+	return exp128(a, e, GF128One(), GF128.Mul)
+}
+
+// Inverse returns a^-1. The inverse of 0 is defined as 0.
+func (a GF128) Inverse() GF128 {
+	return a.Exp(orderMinusOne128)
+}
+
+// GF128Polyval is an element of GF(2^128) in the bit order of POLYVAL.
+//
+// The field is defined by x^128 + x^127 + x^126 + x^121 + 1 and the bytes
+// are little-endian, so bit i of the 128-bit integer is the coefficient of
+// x^i (RFC 8452 section 3). The zero value is 0.
+type GF128Polyval struct {
+	// g is ByteReverse of the element, which lives in the GCM field.
+	// ByteReverse(a*b) = ByteReverse(a) * ByteReverse(b) * x^-127,
+	// so multiplication is done by mulg.
+	g GF128
+}
+
+// GF128PolyvalFromBytes returns the element represented by 16 bytes in POLYVAL order
+func GF128PolyvalFromBytes(b []byte) (GF128Polyval, error) {
+	if len(b) != 16 {
+		return GF128Polyval{}, errors.New("toyaes: GF128Polyval requires 16 bytes")
+	}
+	r := slices.Clone(b)
+	slices.Reverse(r)
+	return GF128Polyval{GF128{newUint128(r)}}, nil
+}
+
+// GF128PolyvalFromBig returns the element whose coefficient of x^i is bit i of n.
+// n must satisfy 0 <= n < 2^128.
+func GF128PolyvalFromBig(n *big.Int) (GF128Polyval, error) {
+	b, err := bigTo16(n)
+	if err != nil {
+		return GF128Polyval{}, err
+	}
+	return GF128Polyval{GF128{newUint128(b[:])}}, nil
+}
+
+// GF128PolyvalOne returns 1
+func GF128PolyvalOne() GF128Polyval {
+	return GF128Polyval{GF128{uint128{0, 1}}}
+}
+
+// Bytes returns the 16 bytes representation in POLYVAL order
+func (a GF128Polyval) Bytes() [16]byte {
+	b := a.g.Bytes()
+	slices.Reverse(b[:])
+	return b
+}
+
+// Big returns the integer whose bit i is the coefficient of x^i
+func (a GF128Polyval) Big() *big.Int {
+	b := a.g.Bytes()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// String returns Bytes in hex
+func (a GF128Polyval) String() string {
+	b := a.Bytes()
+	return hex.EncodeToString(b[:])
+}
+
+// IsZero reports whether a is 0
+func (a GF128Polyval) IsZero() bool { return a.g.IsZero() }
+
+// Add returns a + b
+func (a GF128Polyval) Add(b GF128Polyval) GF128Polyval {
+	return GF128Polyval{a.g.Add(b.g)}
+}
+
+// Mul returns a * b
+func (a GF128Polyval) Mul(b GF128Polyval) GF128Polyval {
+	return GF128Polyval{a.g.Mul(b.g).Mul(gcmXInv127)}
+}
+
+// Dot returns a * b * x^-128, the multiplication used by POLYVAL (RFC 8452 section 3)
+func (a GF128Polyval) Dot(b GF128Polyval) GF128Polyval {
+	return GF128Polyval{a.g.Mul(b.g).Mul(gcmX)}
+}
+
+// Square returns a * a
+func (a GF128Polyval) Square() GF128Polyval { return a.Mul(a) }
+
+// Exp returns a^e. If e is negative, a must not be 0.
+func (a GF128Polyval) Exp(e *big.Int) GF128Polyval {
+	return exp128(a, e, GF128PolyvalOne(), GF128Polyval.Mul)
+}
+
+// Inverse returns a^-1. The inverse of 0 is defined as 0.
+func (a GF128Polyval) Inverse() GF128Polyval {
+	return a.Exp(orderMinusOne128)
+}
+
+// orderMinusOne128 is 2^128 - 2. a^(2^128-2) = a^-1 for a != 0.
+var orderMinusOne128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(2))
+
+var (
+	// gcmX is x in GCM order
+	gcmX = GF128{uint128{1 << 62, 0}}
+	// gcmXInv127 is x^-127 in GCM order
+	gcmXInv127 = gcmX.Inverse().Exp(big.NewInt(127))
+)
+
+// exp128 computes a^e by square and multiply
+func exp128[T any](a T, e *big.Int, one T, mul func(T, T) T) T {
+	if e.Sign() < 0 {
+		a = exp128(a, orderMinusOne128, one, mul)
+		e = new(big.Int).Neg(e)
+	}
+	r := one
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = mul(r, r)
+		if e.Bit(i) == 1 {
+			r = mul(r, a)
+		}
+	}
+	return r
+}
+
+func bigTo16(n *big.Int) ([16]byte, error) {
+	var b [16]byte
+	if n.Sign() < 0 || n.BitLen() > 128 {
+		return b, errors.New("toyaes: GF(2^128) element out of range")
+	}
+	n.FillBytes(b[:])
+	return b, nil
+}
@@ -0,0 +1,166 @@
+package toyaes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// clmulModBig multiplies a and b as polynomials over GF(2) and reduces by m
+func clmulModBig(a, b, m *big.Int) *big.Int {
+	r := new(big.Int)
+	for i := 0; i < b.BitLen(); i++ {
+		if b.Bit(i) == 1 {
+			r.Xor(r, new(big.Int).Lsh(a, uint(i)))
+		}
+	}
+	for d := r.BitLen() - 1; d >= m.BitLen()-1; d = r.BitLen() - 1 {
+		r.Xor(r, new(big.Int).Lsh(m, uint(d-(m.BitLen()-1))))
+	}
+	return r
+}
+
+func randomBig128(t *testing.T) *big.Int {
+	t.Helper()
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return new(big.Int).SetBytes(b)
+}
+
+func bigFromHex(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 16)
+	return n
+}
+
+func TestGF128_Mul(t *testing.T) {
+	t.Parallel()
+
+	// x^128 + x^7 + x^2 + x + 1
+	m := bigFromHex("100000000000000000000000000000087")
+	for i := 0; i < 200; i++ {
+		x, y := randomBig128(t), randomBig128(t)
+		a, _ := GF128FromBig(x)
+		b, _ := GF128FromBig(y)
+		if got, want := a.Mul(b).Big(), clmulModBig(x, y, m); got.Cmp(want) != 0 {
+			t.Fatalf("%v * %v = %x, want %x", a, b, got, want)
+		}
+	}
+}
+
+func TestGF128Polyval_Mul(t *testing.T) {
+	t.Parallel()
+
+	// x^128 + x^127 + x^126 + x^121 + 1
+	m := bigFromHex("1c2000000000000000000000000000001")
+	for i := 0; i < 200; i++ {
+		x, y := randomBig128(t), randomBig128(t)
+		a, _ := GF128PolyvalFromBig(x)
+		b, _ := GF128PolyvalFromBig(y)
+		if got, want := a.Mul(b).Big(), clmulModBig(x, y, m); got.Cmp(want) != 0 {
+			t.Fatalf("%v * %v = %x, want %x", a, b, got, want)
+		}
+	}
+}
+
+func TestGF128_BytesAndBig(t *testing.T) {
+	t.Parallel()
+
+	one := GF128One()
+	if got := one.Big(); got.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("GF128One().Big() = %v, want 1", got)
+	}
+	if got := one.String(); got != "80000000000000000000000000000000" {
+		t.Errorf("GF128One() = %s", got)
+	}
+	pone := GF128PolyvalOne()
+	if got := pone.String(); got != "01000000000000000000000000000000" {
+		t.Errorf("GF128PolyvalOne() = %s", got)
+	}
+
+	b, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	a, err := GF128FromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Bytes(); hex.EncodeToString(got[:]) != hex.EncodeToString(b) {
+		t.Errorf("Bytes() = %x, want %x", got, b)
+	}
+	if got, _ := GF128FromBig(a.Big()); got != a {
+		t.Errorf("GF128FromBig(Big()) = %v, want %v", got, a)
+	}
+	p, err := GF128PolyvalFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Bytes(); hex.EncodeToString(got[:]) != hex.EncodeToString(b) {
+		t.Errorf("Bytes() = %x, want %x", got, b)
+	}
+	if got, _ := GF128PolyvalFromBig(p.Big()); got != p {
+		t.Errorf("GF128PolyvalFromBig(Big()) = %v, want %v", got, p)
+	}
+
+	if _, err := GF128FromBytes(b[:15]); err == nil {
+		t.Error("GF128FromBytes accepts 15 bytes")
+	}
+	if _, err := GF128FromBig(new(big.Int).Lsh(big.NewInt(1), 128)); err == nil {
+		t.Error("GF128FromBig accepts 2^128")
+	}
+	if _, err := GF128PolyvalFromBig(big.NewInt(-1)); err == nil {
+		t.Error("GF128PolyvalFromBig accepts -1")
+	}
+}
+
+func TestGF128_InverseAndExp(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 20; i++ {
+		a, _ := GF128FromBig(randomBig128(t))
+		if got := a.Mul(a.Inverse()); got != GF128One() {
+			t.Fatalf("%v * %v^-1 = %v", a, a, got)
+		}
+		if got, want := a.Exp(big.NewInt(3)), a.Square().Mul(a); got != want {
+			t.Fatalf("%v^3 = %v, want %v", a, got, want)
+		}
+		if got, want := a.Exp(big.NewInt(-2)), a.Inverse().Square(); got != want {
+			t.Fatalf("%v^-2 = %v, want %v", a, got, want)
+		}
+
+		p, _ := GF128PolyvalFromBig(randomBig128(t))
+		if got := p.Mul(p.Inverse()); got != GF128PolyvalOne() {
+			t.Fatalf("%v * %v^-1 = %v", p, p, got)
+		}
+		if got, want := p.Exp(big.NewInt(3)), p.Square().Mul(p); got != want {
+			t.Fatalf("%v^3 = %v, want %v", p, got, want)
+		}
+	}
+	if got := (GF128{}).Inverse(); !got.IsZero() {
+		t.Errorf("0^-1 = %v, want 0", got)
+	}
+}
+
+func TestGF128Polyval_Dot(t *testing.T) {
+	t.Parallel()
+
+	// RFC 8452 Appendix A
+	h := mustPolyval(t, "25629347589242761d31f826ba4b757b")
+	x1 := mustPolyval(t, "4f4f95668c83dfb6401762bb2d01a262")
+	x2 := mustPolyval(t, "d1a24ddd2721d006bbe45f20d3c9f362")
+	var s GF128Polyval
+	for _, x := range []GF128Polyval{x1, x2} {
+		s = s.Add(x).Dot(h)
+	}
+	if got, want := s.String(), "f7a3b47b846119fae5b7866cf5e5b77e"; got != want {
+		t.Errorf("POLYVAL = %s, want %s", got, want)
+	}
+}
+
+func mustPolyval(t *testing.T, s string) GF128Polyval {
+	t.Helper()
+	b, _ := hex.DecodeString(s)
+	p, err := GF128PolyvalFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,186 @@
+package toyaes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AESPolynomial is m(x) = x^8 + x^4 + x^3 + x + 1 (FIPS-197 section 4.2)
+const AESPolynomial = 0x11b
+
+// AESField is GF(2^8) defined by AESPolynomial
+var AESField = mustGF256(AESPolynomial)
+
+// GF256 is GF(2^8) defined by an irreducible polynomial of degree 8.
+//
+// Elements are bytes whose bit i is the coefficient of x^i.
+// Multiplication and division use log/antilog tables built from a generator
+// of the multiplicative group.
+type GF256 struct {
+	poly      uint16
+	generator byte
+	// exp is doubled so that exp[log[x]+log[y]] needs no reduction
+	exp [510]byte
+	log [256]byte
+}
+
+// NewGF256 returns GF(2^8) defined by poly.
+// poly must be an irreducible polynomial of degree 8 such as AESPolynomial.
+func NewGF256(poly uint16) (*GF256, error) {
+	if poly>>8 != 1 {
+		return nil, fmt.Errorf("toyaes: polynomial %#x is not of degree 8", poly)
+	}
+	if !irreducible8(poly) {
+		return nil, fmt.Errorf("toyaes: polynomial %#x is reducible", poly)
+	}
+	f := &GF256{poly: poly}
+	for g := 2; g < 256; g++ {
+		if f.fillTables(byte(g)) {
+			f.generator = byte(g)
+			return f, nil
+		}
+	}
+	// every finite field has a primitive element
+	return nil, errors.New("toyaes: no generator found")
+}
+
+func mustGF256(poly uint16) *GF256 {
+	f, err := NewGF256(poly)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// clmul8 multiplies x and y without reduction
+func clmul8(x, y uint16) uint16 {
+	var sum uint16
+	for ; y != 0; y >>= 1 {
+		if y&1 == 1 {
+			sum ^= x
+		}
+		x <<= 1
+	}
+	return sum
+}
+
+// polyMod returns x mod m over GF(2)
+func polyMod(x, m uint16) uint16 {
+	dm := bitLen16(m)
+	for d := bitLen16(x); d >= dm; d = bitLen16(x) {
+		x ^= m << (d - dm)
+	}
+	return x
+}
+
+func bitLen16(x uint16) int {
+	n := 0
+	for ; x != 0; x >>= 1 {
+		n++
+	}
+	return n
+}
+
+// irreducible8 reports whether poly of degree 8 has no factor of degree 1 to 4
+func irreducible8(poly uint16) bool {
+	for d := uint16(2); d < 32; d++ {
+		if polyMod(poly, d) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mulSlow multiplies x and y by shift and add
+func (f *GF256) mulSlow(x, y byte) byte {
+	return byte(polyMod(clmul8(uint16(x), uint16(y)), f.poly))
+}
+
+// fillTables fills exp and log with powers of g.
+// It reports false if g does not generate the multiplicative group.
+func (f *GF256) fillTables(g byte) bool {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		if i > 0 && x == 1 {
+			return false
+		}
+		f.exp[i] = x
+		f.exp[i+255] = x
+		f.log[x] = byte(i)
+		x = f.mulSlow(x, g)
+	}
+	return true
+}
+
+// Polynomial returns the modulus polynomial
+func (f *GF256) Polynomial() uint16 { return f.poly }
+
+// Generator returns the generator used as the base of Log and Exp
+func (f *GF256) Generator() byte { return f.generator }
+
+// Add returns x + y. Addition and subtraction are both xor.
+func (*GF256) Add(x, y byte) byte { return x ^ y }
+
+// Mul returns x * y
+func (f *GF256) Mul(x, y byte) byte {
+	if x == 0 || y == 0 {
+		return 0
+	}
+	return f.exp[int(f.log[x])+int(f.log[y])]
+}
+
+// Inverse returns the multiplicative inverse of x.
+// As in the AES S-box, the inverse of 0 is defined as 0.
+func (f *GF256) Inverse(x byte) byte {
+	if x == 0 {
+		return 0
+	}
+	return f.exp[255-int(f.log[x])]
+}
+
+// Div returns x / y. It panics if y is 0.
+func (f *GF256) Div(x, y byte) byte {
+	if y == 0 {
+		panic("toyaes: division by zero")
+	}
+	if x == 0 {
+		return 0
+	}
+	return f.exp[int(f.log[x])+255-int(f.log[y])]
+}
+
+// Pow returns x^n. n may be negative when x is not 0.
+func (f *GF256) Pow(x byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if x == 0 {
+		if n < 0 {
+			panic("toyaes: division by zero")
+		}
+		return 0
+	}
+	e := (int(f.log[x]) * (n % 255)) % 255
+	if e < 0 {
+		e += 255
+	}
+	return f.exp[e]
+}
+
+// Log returns i such that Generator()^i = x.
+// ok is false if x is 0, which has no logarithm.
+func (f *GF256) Log(x byte) (i byte, ok bool) {
+	if x == 0 {
+		return 0, false
+	}
+	return f.log[x], true
+}
+
+// Exp returns Generator()^i
+func (f *GF256) Exp(i int) byte {
+	i %= 255
+	if i < 0 {
+		i += 255
+	}
+	return f.exp[i]
+}
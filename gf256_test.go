@@ -0,0 +1,130 @@
+package toyaes
+
+import (
+	"testing"
+)
+
+func TestNewGF256(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		poly    uint16
+		wantErr bool
+	}{
+		{"AES", AESPolynomial, false},
+		{"Reed-Solomon", 0x11d, false},
+		{"x^8", 0x100, true},
+		{"(x^4+x+1)^2", 0x105, true},
+		{"divisible by x+1", 0x113, true},
+		{"degree 7", 0x83, true},
+		{"degree 9", 0x211, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGF256(tt.poly)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewGF256(%#x) error = %v, wantErr %v", tt.poly, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGF256_Mul(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		x     byte
+		table []byte
+	}{
+		{0x02, mul0x02}, {0x03, mul0x03}, {0x09, mul0x09},
+		{0x0b, mul0x0b}, {0x0d, mul0x0d}, {0x0e, mul0x0e},
+	} {
+		for y := 0; y < 256; y++ {
+			if got := AESField.Mul(v.x, byte(y)); got != v.table[y] {
+				t.Fatalf("Mul(%#02x, %#02x) = %#02x, want %#02x", v.x, y, got, v.table[y])
+			}
+		}
+	}
+	for x := 0; x < 256; x++ {
+		for y := 0; y < 256; y++ {
+			if got, want := AESField.Mul(byte(x), byte(y)), AESField.mulSlow(byte(x), byte(y)); got != want {
+				t.Fatalf("Mul(%#02x, %#02x) = %#02x, want %#02x", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestGF256_InverseAndDiv(t *testing.T) {
+	t.Parallel()
+
+	for _, poly := range []uint16{AESPolynomial, 0x11d} {
+		f, err := NewGF256(poly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Inverse(0) != 0 {
+			t.Errorf("Inverse(0) = %#02x, want 0", f.Inverse(0))
+		}
+		for x := 1; x < 256; x++ {
+			inv := f.Inverse(byte(x))
+			if got := f.Mul(byte(x), inv); got != 1 {
+				t.Fatalf("poly=%#x: %#02x * Inverse(%#02x) = %#02x, want 1", poly, x, x, got)
+			}
+			for y := 0; y < 256; y++ {
+				if got := f.Mul(f.Div(byte(y), byte(x)), byte(x)); got != byte(y) {
+					t.Fatalf("poly=%#x: (%#02x / %#02x) * %#02x = %#02x", poly, y, x, x, got)
+				}
+			}
+		}
+	}
+}
+
+func TestGF256_SboxFromInverse(t *testing.T) {
+	t.Parallel()
+
+	// FIPS-197 section 5.1.1: affine transformation of the inverse
+	rotl := func(b byte, n uint) byte { return b<<n | b>>(8-n) }
+	for x := 0; x < 256; x++ {
+		b := AESField.Inverse(byte(x))
+		s := b ^ rotl(b, 1) ^ rotl(b, 2) ^ rotl(b, 3) ^ rotl(b, 4) ^ 0x63
+		if s != sbox[x] {
+			t.Fatalf("sbox[%#02x] = %#02x, want %#02x", x, sbox[x], s)
+		}
+	}
+}
+
+func TestGF256_PowLogExp(t *testing.T) {
+	t.Parallel()
+
+	f := AESField
+	if f.Generator() != 0x03 {
+		t.Errorf("Generator() = %#02x, want 0x03", f.Generator())
+	}
+	for i, want := range powx {
+		if got := f.Pow(0x02, i); got != want {
+			t.Errorf("Pow(0x02, %d) = %#02x, want %#02x", i, got, want)
+		}
+	}
+	for x := 1; x < 256; x++ {
+		l, ok := f.Log(byte(x))
+		if !ok {
+			t.Fatalf("Log(%#02x) is not defined", x)
+		}
+		if got := f.Exp(int(l)); got != byte(x) {
+			t.Fatalf("Exp(Log(%#02x)) = %#02x", x, got)
+		}
+		if got := f.Pow(byte(x), -1); got != f.Inverse(byte(x)) {
+			t.Fatalf("Pow(%#02x, -1) = %#02x, want %#02x", x, got, f.Inverse(byte(x)))
+		}
+		if got := f.Pow(byte(x), 255); got != 1 {
+			t.Fatalf("Pow(%#02x, 255) = %#02x, want 1", x, got)
+		}
+	}
+	if _, ok := f.Log(0); ok {
+		t.Error("Log(0) is defined")
+	}
+	if got := f.Pow(0, 0); got != 1 {
+		t.Errorf("Pow(0, 0) = %#02x, want 1", got)
+	}
+}
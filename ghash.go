@@ -0,0 +1,163 @@
+package toyaes
+
+// GHASHMethod is the implementation of the multiplication by H in GHASH
+type GHASHMethod int
+
+const (
+	// GHASHShoup4 uses a table of 16 multiples of H per key (Shoup's 4-bit method)
+	GHASHShoup4 GHASHMethod = iota
+	// GHASHShoup8 uses a table of 256 multiples of H per key (Shoup's 8-bit method)
+	GHASHShoup8
+	// GHASHBitwise is the reference, one bit at a time by mulg
+	GHASHBitwise
+)
+
+// ghashMultiplier multiplies by H
+type ghashMultiplier interface {
+	mulH(x uint128) uint128
+}
+
+func newGHASHMultiplier(method GHASHMethod, h uint128) ghashMultiplier {
+	switch method {
+	case GHASHShoup8:
+		return newShoup8(h)
+	case GHASHBitwise:
+		return bitwiseMul{h}
+	default:
+		return newShoup4(h)
+	}
+}
+
+// ghashKey holds the multipliers for the powers of H
+type ghashKey struct {
+	// pow[i] multiplies by H^(i+1). len(pow) is the number of blocks
+	// processed at once.
+	pow []ghashMultiplier
+}
+
+func newGHASHKey(method GHASHMethod, h uint128, aggregation int) *ghashKey {
+	k := max(aggregation, 1)
+	g := &ghashKey{pow: make([]ghashMultiplier, k)}
+	hi := h
+	for i := range g.pow {
+		g.pow[i] = newGHASHMultiplier(method, hi)
+		hi = mulg(hi, h)
+	}
+	return g
+}
+
+// update absorbs in, padded with zeros to a multiple of 16 bytes, into x
+func (g *ghashKey) update(x uint128, in []byte) uint128 {
+	k := len(g.pow)
+	for k > 1 && len(in) >= 16*k {
+		// (x + X1)*H^k + X2*H^(k-1) + ... + Xk*H
+		var sum uint128
+		for i := 0; i < k; i++ {
+			v := newUint128(in[16*i:])
+			if i == 0 {
+				v = add(v, x)
+			}
+			sum = add(sum, g.pow[k-1-i].mulH(v))
+		}
+		x = sum
+		in = in[16*k:]
+	}
+	for len(in) > 0 {
+		var b [16]byte
+		n := copy(b[:], in)
+		x = g.pow[0].mulH(add(x, newUint128(b[:])))
+		in = in[n:]
+	}
+	return x
+}
+
+type bitwiseMul struct {
+	h uint128
+}
+
+func (m bitwiseMul) mulH(x uint128) uint128 { return mulg(x, m.h) }
+
+// Shoup's method computes X*H by Horner's rule over w-bit chunks of X from
+// the highest degree:
+//
+//	Z = Z * x^w + X_k * H
+//
+// X_k * H is looked up from the table of the key. In GCM order multiplying
+// by x^w is a right shift by w, and the w bits shifted out are reduced by
+// a table independent of the key.
+
+// shoupReduction returns red[r] = r * x^w for r in the lowest w bits,
+// i.e. the coefficients of x^(128-w) .. x^127 multiplied by x^w
+func shoupReduction(w uint) []uint128 {
+	xw := uint128{1 << (63 - w), 0}
+	red := make([]uint128, 1<<w)
+	for r := range red {
+		red[r] = mulg(uint128{0, uint64(r)}, xw)
+	}
+	return red
+}
+
+// shoupTable returns m[n] = n * H where n is w bits at the lowest degree
+func shoupTable(h uint128, w uint) []uint128 {
+	m := make([]uint128, 1<<w)
+	for n := range m {
+		m[n] = mulg(uint128{uint64(n) << (64 - w), 0}, h)
+	}
+	return m
+}
+
+var (
+	shoup4Reduction = shoupReduction(4)
+	shoup8Reduction = shoupReduction(8)
+)
+
+type shoup4 struct {
+	m [16]uint128
+}
+
+func newShoup4(h uint128) *shoup4 {
+	s := &shoup4{}
+	copy(s.m[:], shoupTable(h, 4))
+	return s
+}
+
+func (s *shoup4) mulH(x uint128) uint128 {
+	var z uint128
+	for i := 0; i < 32; i++ {
+		// the lowest nibble of x.rhs has the highest degree
+		var n uint64
+		if i < 16 {
+			n = x.rhs >> (4 * i) & 0xf
+		} else {
+			n = x.lhs >> (4 * (i - 16)) & 0xf
+		}
+		r := z.rhs & 0xf
+		z = z.rightShift(4).xor(shoup4Reduction[r]).xor(s.m[n])
+	}
+	return z
+}
+
+type shoup8 struct {
+	m [256]uint128
+}
+
+func newShoup8(h uint128) *shoup8 {
+	s := &shoup8{}
+	copy(s.m[:], shoupTable(h, 8))
+	return s
+}
+
+func (s *shoup8) mulH(x uint128) uint128 {
+	var z uint128
+	for i := 0; i < 16; i++ {
+		var n uint64
+		if i < 8 {
+			n = x.rhs >> (8 * i) & 0xff
+		} else {
+			n = x.lhs >> (8 * (i - 8)) & 0xff
+		}
+		r := z.rhs & 0xff
+		z = z.rightShift(8).xor(shoup8Reduction[r]).xor(s.m[n])
+	}
+	return z
+}
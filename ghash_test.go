@@ -0,0 +1,124 @@
+package toyaes
+
+import (
+	"crypto/aes"
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"reflect"
+	"testing"
+)
+
+func randomUint128() uint128 {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return newUint128(b)
+}
+
+func Test_ghashMultiplier(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 100; i++ {
+		h := randomUint128()
+		for _, method := range []GHASHMethod{GHASHShoup4, GHASHShoup8, GHASHBitwise} {
+			m := newGHASHMultiplier(method, h)
+			for j := 0; j < 10; j++ {
+				x := randomUint128()
+				if got, want := m.mulH(x), mulg(x, h); got != want {
+					t.Fatalf("method=%d: %v * %v = %v, want %v", method, x, h, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSeal_GHASHMethod(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	nonce := make([]byte, 12)
+	for _, method := range []GHASHMethod{GHASHShoup4, GHASHShoup8, GHASHBitwise} {
+		for _, n := range []int{0, 1, 16, 33, 100} {
+			_, _ = rand.Read(key)
+			_, _ = rand.Read(nonce)
+			plaintext := make([]byte, n)
+			additionalData := make([]byte, n/2)
+			_, _ = rand.Read(plaintext)
+			_, _ = rand.Read(additionalData)
+
+			got := NewGCM(NewToyAES(key), WithGHASH(method)).Seal(nil, nonce, plaintext, additionalData)
+
+			aesb, _ := aes.NewCipher(key)
+			aead, _ := ccipher.NewGCM(aesb)
+			want := aead.Seal(nil, nonce, plaintext, additionalData)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("method=%d: got =%X, want=%X\n", method, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkGHASH(b *testing.B) {
+	in := make([]byte, 16*1024)
+	_, _ = rand.Read(in)
+	h := randomUint128()
+
+	for _, bm := range []struct {
+		name        string
+		method      GHASHMethod
+		aggregation int
+	}{
+		{"bitwise", GHASHBitwise, 1},
+		{"shoup4", GHASHShoup4, 1},
+		{"shoup8", GHASHShoup8, 1},
+		{"shoup8 aggregated by 8", GHASHShoup8, 8},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			m := newGHASHKey(bm.method, h, bm.aggregation)
+			b.SetBytes(int64(len(in)))
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				ghash(m, in, nil)
+			}
+		})
+	}
+}
+
+func Test_ghashKey_aggregation(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 15, 16, 17, 64, 100, 256} {
+		in := make([]byte, n)
+		ad := make([]byte, n/3)
+		_, _ = rand.Read(in)
+		_, _ = rand.Read(ad)
+		h := randomUint128()
+		want := ghash(newGHASHKey(GHASHBitwise, h, 1), in, ad)
+		for _, k := range []int{0, 2, 3, 4, 8} {
+			for _, method := range []GHASHMethod{GHASHShoup4, GHASHBitwise} {
+				if got := ghash(newGHASHKey(method, h, k), in, ad); got != want {
+					t.Fatalf("len=%d, k=%d, method=%d: got=%X, want=%X", n, k, method, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSeal_GHASHAggregation(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	nonce := make([]byte, 12)
+	plaintext := make([]byte, 1000)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(nonce)
+	_, _ = rand.Read(plaintext)
+
+	got := NewGCM(NewToyAES(key), WithGHASHAggregation(4)).Seal(nil, nonce, plaintext, nil)
+
+	aesb, _ := aes.NewCipher(key)
+	aead, _ := ccipher.NewGCM(aesb)
+	want := aead.Seal(nil, nonce, plaintext, nil)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got =%X, want=%X\n", got, want)
+	}
+}
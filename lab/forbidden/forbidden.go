@@ -0,0 +1,116 @@
+// Package forbidden demonstrates the "forbidden attack" on GCM.
+//
+// When two messages are sealed under the same key and nonce, their tags share
+// the mask E(K, J0). Adding the two tag equations cancels the mask and leaves
+// a polynomial in the authentication key H, whose roots can be computed.
+// With H and one of the messages, a valid tag can be computed for any
+// ciphertext under that nonce.
+//
+// See: A. Joux, "Authentication Failures in NIST version of GCM"
+package forbidden
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+const tagSize = 16
+
+// Sample is the output of Seal under a reused key and nonce
+type Sample struct {
+	AdditionalData []byte
+	// Ciphertext is the ciphertext followed by the tag
+	Ciphertext []byte
+}
+
+func (s Sample) split() (ciphertext, tag []byte, err error) {
+	if len(s.Ciphertext) < tagSize {
+		return nil, nil, errors.New("forbidden: ciphertext is shorter than the tag")
+	}
+	n := len(s.Ciphertext) - tagSize
+	return s.Ciphertext[:n], s.Ciphertext[n:], nil
+}
+
+// blocks returns the GHASH input: A || 0* || C || 0* || len(A) || len(C)
+func blocks(additionalData, ciphertext []byte) []toyaes.GF128 {
+	var bs []toyaes.GF128
+	for _, in := range [][]byte{additionalData, ciphertext} {
+		for i := 0; i < len(in); i += 16 {
+			var b [16]byte
+			copy(b[:], in[i:])
+			e, _ := toyaes.GF128FromBytes(b[:])
+			bs = append(bs, e)
+		}
+	}
+	var l [16]byte
+	binary.BigEndian.PutUint64(l[:8], uint64(len(additionalData))*8)
+	binary.BigEndian.PutUint64(l[8:], uint64(len(ciphertext))*8)
+	e, _ := toyaes.GF128FromBytes(l[:])
+	return append(bs, e)
+}
+
+// tagPoly returns the polynomial T(Y) = X_1 Y^m + ... + X_m Y + tag.
+// T(H) = E(K, J0) for the correct H.
+func tagPoly(s Sample) (poly, error) {
+	ct, tag, err := s.split()
+	if err != nil {
+		return nil, err
+	}
+	bs := blocks(s.AdditionalData, ct)
+	p := make(poly, len(bs)+1)
+	for i, b := range bs {
+		p[len(bs)-i] = b
+	}
+	p[0], _ = toyaes.GF128FromBytes(tag)
+	return p, nil
+}
+
+// ghash computes GHASH with the key h
+func ghash(h toyaes.GF128, additionalData, ciphertext []byte) toyaes.GF128 {
+	var x toyaes.GF128
+	for _, b := range blocks(additionalData, ciphertext) {
+		x = x.Add(b).Mul(h)
+	}
+	return x
+}
+
+// RecoverAuthKeys returns the candidates of the authentication key H
+// from two samples sealed under the same key and nonce.
+//
+// The correct H is always one of the candidates. When there are several
+// candidates, a third sample or a forgery attempt tells them apart.
+func RecoverAuthKeys(s1, s2 Sample) ([]toyaes.GF128, error) {
+	p1, err := tagPoly(s1)
+	if err != nil {
+		return nil, err
+	}
+	p2, err := tagPoly(s2)
+	if err != nil {
+		return nil, err
+	}
+	// T1(H) + T2(H) = E(K, J0) + E(K, J0) = 0
+	f := p1.add(p2)
+	if f.degree() < 1 {
+		return nil, errors.New("forbidden: samples must differ")
+	}
+	return roots(f)
+}
+
+// Forge returns ciphertext followed by a tag that is valid under the key and
+// nonce of known, provided that h is the authentication key.
+func Forge(h toyaes.GF128, known Sample, additionalData, ciphertext []byte) ([]byte, error) {
+	ct, tag, err := known.split()
+	if err != nil {
+		return nil, err
+	}
+	t, _ := toyaes.GF128FromBytes(tag)
+	// E(K, J0) = tag + GHASH(H, A, C)
+	mask := t.Add(ghash(h, known.AdditionalData, ct))
+	forged := ghash(h, additionalData, ciphertext).Add(mask).Bytes()
+
+	out := make([]byte, 0, len(ciphertext)+tagSize)
+	out = append(out, ciphertext...)
+	return append(out, forged[:]...), nil
+}
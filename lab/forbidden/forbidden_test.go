@@ -0,0 +1,143 @@
+package forbidden
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"testing"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+func Test_roots(t *testing.T) {
+	t.Parallel()
+
+	var want []toyaes.GF128
+	p := poly{toyaes.GF128One()}
+	for i := 0; i < 5; i++ {
+		r, _ := randomElement()
+		want = append(want, r)
+		p = p.mul(poly{r, toyaes.GF128One()})
+	}
+	// a factor without roots: Y^2 + Y + c has no root when Tr(c) = 1,
+	// so multiply by an irreducible quadratic found by trial
+	for {
+		c, _ := randomElement()
+		q := poly{c, toyaes.GF128One(), toyaes.GF128One()}
+		if rs, _ := roots(q); len(rs) == 0 {
+			p = p.mul(q)
+			break
+		}
+	}
+
+	got, err := roots(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d roots, want %d", len(got), len(want))
+	}
+	for _, w := range want {
+		if !contains(got, w) {
+			t.Errorf("root %v is not found", w)
+		}
+	}
+}
+
+func contains(s []toyaes.GF128, v toyaes.GF128) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRecoverAuthKeys(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	nonce := make([]byte, 12)
+	for i := 0; i < 5; i++ {
+		_, _ = rand.Read(key)
+		_, _ = rand.Read(nonce)
+		block := toyaes.NewToyAES(key)
+		aead := toyaes.NewGCM(block)
+
+		p1 := []byte("transfer 100 to alice")
+		p2 := []byte("transfer 200 to bob, then close the account")
+		s1 := Sample{AdditionalData: []byte("header"), Ciphertext: aead.Seal(nil, nonce, p1, []byte("header"))}
+		s2 := Sample{Ciphertext: aead.Seal(nil, nonce, p2, nil)}
+
+		candidates, err := RecoverAuthKeys(s1, s2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hk := make([]byte, 16)
+		block.Encrypt(hk, make([]byte, 16))
+		h, _ := toyaes.GF128FromBytes(hk)
+		if !contains(candidates, h) {
+			t.Fatalf("H=%v is not in %v", h, candidates)
+		}
+
+		// CTR is malleable: flip the plaintext without knowing the key
+		target := []byte("transfer 999 to eve!!")
+		forgedCT := make([]byte, len(p1))
+		subtle.XORBytes(forgedCT, s1.Ciphertext[:len(p1)], p1)
+		subtle.XORBytes(forgedCT, forgedCT, target)
+
+		forged, err := Forge(h, s1, []byte("forged"), forgedCT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := aead.Open(nil, nonce, forged, []byte("forged"))
+		if err != nil {
+			t.Fatalf("forged message is rejected: %v", err)
+		}
+		if !bytes.Equal(got, target) {
+			t.Fatalf("got=%q, want=%q", got, target)
+		}
+	}
+}
+
+func TestRecoverAuthKeys_identicalSamples(t *testing.T) {
+	t.Parallel()
+
+	aead := toyaes.NewGCM(toyaes.NewToyAES(make([]byte, 16)))
+	s := Sample{Ciphertext: aead.Seal(nil, make([]byte, 12), []byte("same"), nil)}
+	if _, err := RecoverAuthKeys(s, s); err == nil {
+		t.Error("identical samples are accepted")
+	}
+	if _, err := RecoverAuthKeys(Sample{Ciphertext: []byte("short")}, s); err == nil {
+		t.Error("short ciphertext is accepted")
+	}
+}
+
+func Example() {
+	key := []byte("0123456789abcdef")
+	nonce := []byte("reused nonce")
+	aead := toyaes.NewGCM(toyaes.NewToyAES(key))
+
+	// the same nonce is used twice
+	p1 := []byte("pay 10 to alice")
+	s1 := Sample{Ciphertext: aead.Seal(nil, nonce, p1, nil)}
+	s2 := Sample{Ciphertext: aead.Seal(nil, nonce, []byte("pay 20 to bob"), nil)}
+
+	candidates, _ := RecoverAuthKeys(s1, s2)
+
+	target := []byte("pay 99 to eve!!")
+	ct := make([]byte, len(p1))
+	subtle.XORBytes(ct, s1.Ciphertext[:len(p1)], p1)
+	subtle.XORBytes(ct, ct, target)
+
+	// try every candidate against the receiver
+	for _, h := range candidates {
+		forged, _ := Forge(h, s1, nil, ct)
+		if pt, err := aead.Open(nil, nonce, forged, nil); err == nil {
+			fmt.Printf("receiver accepted: %s\n", pt)
+		}
+	}
+	// Output:
+	// receiver accepted: pay 99 to eve!!
+}
@@ -0,0 +1,162 @@
+package forbidden
+
+import (
+	"crypto/rand"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+// poly is a polynomial over GF(2^128).
+// poly[i] is the coefficient of Y^i.
+type poly []toyaes.GF128
+
+func (p poly) trim() poly {
+	for len(p) > 0 && p[len(p)-1].IsZero() {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// degree returns -1 for the zero polynomial
+func (p poly) degree() int { return len(p.trim()) - 1 }
+
+func (p poly) add(q poly) poly {
+	if len(p) < len(q) {
+		p, q = q, p
+	}
+	r := make(poly, len(p))
+	copy(r, p)
+	for i, c := range q {
+		r[i] = r[i].Add(c)
+	}
+	return r.trim()
+}
+
+func (p poly) mul(q poly) poly {
+	if len(p) == 0 || len(q) == 0 {
+		return nil
+	}
+	r := make(poly, len(p)+len(q)-1)
+	for i, a := range p {
+		if a.IsZero() {
+			continue
+		}
+		for j, b := range q {
+			r[i+j] = r[i+j].Add(a.Mul(b))
+		}
+	}
+	return r.trim()
+}
+
+// divMod returns quotient and remainder of p / q. q must not be zero.
+func (p poly) divMod(q poly) (poly, poly) {
+	q = q.trim()
+	r := append(poly(nil), p.trim()...)
+	if len(r) < len(q) {
+		return nil, r
+	}
+	inv := q[len(q)-1].Inverse()
+	quo := make(poly, len(r)-len(q)+1)
+	for d := len(r) - 1; d >= len(q)-1; d-- {
+		c := r[d].Mul(inv)
+		quo[d-len(q)+1] = c
+		for i, b := range q {
+			r[d-len(q)+1+i] = r[d-len(q)+1+i].Add(c.Mul(b))
+		}
+	}
+	return quo.trim(), r.trim()
+}
+
+func (p poly) mod(q poly) poly {
+	_, r := p.divMod(q)
+	return r
+}
+
+// monic divides p by its leading coefficient
+func (p poly) monic() poly {
+	p = p.trim()
+	if len(p) == 0 {
+		return p
+	}
+	inv := p[len(p)-1].Inverse()
+	r := make(poly, len(p))
+	for i, c := range p {
+		r[i] = c.Mul(inv)
+	}
+	return r
+}
+
+func gcd(a, b poly) poly {
+	a, b = a.trim(), b.trim()
+	for len(b) > 0 {
+		a, b = b, a.mod(b)
+	}
+	return a.monic()
+}
+
+// frobenius returns p^(2^128) mod m by squaring 128 times
+func frobenius(p, m poly) poly {
+	for i := 0; i < 128; i++ {
+		p = p.mul(p).mod(m)
+	}
+	return p
+}
+
+// roots returns all distinct roots of p in GF(2^128)
+func roots(p poly) ([]toyaes.GF128, error) {
+	p = p.monic()
+	if p.degree() < 1 {
+		return nil, nil
+	}
+	// gcd(p, Y^(2^128) - Y) is the product of the linear factors of p
+	y := poly{toyaes.GF128{}, toyaes.GF128One()}
+	g := gcd(p, frobenius(y, p).add(y))
+	return split(g)
+}
+
+// split factors g, a product of distinct linear factors, by Cantor–Zassenhaus
+func split(g poly) ([]toyaes.GF128, error) {
+	switch g.degree() {
+	case -1, 0:
+		return nil, nil
+	case 1:
+		// Y + c
+		return []toyaes.GF128{g[0]}, nil
+	}
+	for {
+		// Tr(aY) = sum (aY)^(2^i) maps every root to 0 or 1 with equal chance,
+		// so gcd(g, Tr(aY)) is a proper factor in about half of the trials
+		a, err := randomElement()
+		if err != nil {
+			return nil, err
+		}
+		t := poly{toyaes.GF128{}, a}
+		sq := t
+		for i := 1; i < 128; i++ {
+			sq = sq.mul(sq).mod(g)
+			t = t.add(sq)
+		}
+		h := gcd(g, t)
+		if d := h.degree(); d < 1 || d == g.degree() {
+			continue
+		}
+		q, _ := g.divMod(h)
+		r1, err := split(h)
+		if err != nil {
+			return nil, err
+		}
+		r2, err := split(q.monic())
+		if err != nil {
+			return nil, err
+		}
+		return append(r1, r2...), nil
+	}
+}
+
+func randomElement() (toyaes.GF128, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return toyaes.GF128{}, err
+	}
+	return toyaes.GF128FromBytes(b)
+}
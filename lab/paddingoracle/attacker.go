@@ -0,0 +1,112 @@
+package paddingoracle
+
+import (
+	"errors"
+
+	"github.com/blck-snwmn/toyaes/padding"
+)
+
+// Step is reported to Attacker.Observe for each recovered byte
+type Step struct {
+	// Block is the index of the ciphertext block, starting at 0 after the IV
+	Block int
+	// Index is the position of the byte within the block
+	Index int
+	// Intermediate is D(K, C_i) at Index
+	Intermediate byte
+	// Plaintext is the recovered plaintext byte
+	Plaintext byte
+	// Queries is the number of oracle queries so far
+	Queries int
+}
+
+// Attacker decrypts ciphertexts by querying a padding oracle
+type Attacker struct {
+	// Oracle reports whether the padding of IV || ciphertext is valid
+	Oracle func(ciphertext []byte) bool
+	// Observe is called for each recovered byte if not nil
+	Observe func(Step)
+
+	queries int
+}
+
+func (a *Attacker) query(prev, block []byte) bool {
+	a.queries++
+	q := make([]byte, 0, 2*blockSize)
+	q = append(q, prev...)
+	q = append(q, block...)
+	return a.Oracle(q)
+}
+
+// Decrypt recovers the plaintext of IV || ciphertext and removes its padding
+func (a *Attacker) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 2*blockSize || len(ciphertext)%blockSize != 0 {
+		return nil, ErrLength
+	}
+	var pt []byte
+	for i := blockSize; i < len(ciphertext); i += blockSize {
+		prev, block := ciphertext[i-blockSize:i], ciphertext[i:i+blockSize]
+		inter, err := a.intermediate(i/blockSize-1, prev, block)
+		if err != nil {
+			return nil, err
+		}
+		for j := range inter {
+			pt = append(pt, inter[j]^prev[j])
+		}
+	}
+	pt, err := padding.PKCS7.Unpad(pt, blockSize)
+	if err != nil {
+		return nil, ErrPadding
+	}
+	return pt, nil
+}
+
+// intermediate recovers D(K, block) byte by byte from the last one.
+// prev is used only to report the plaintext.
+func (a *Attacker) intermediate(n int, prev, block []byte) ([]byte, error) {
+	inter := make([]byte, blockSize)
+	forged := make([]byte, blockSize)
+	for pos := blockSize - 1; pos >= 0; pos-- {
+		padding := byte(blockSize - pos)
+		// make the already known bytes decrypt to the padding value
+		for j := pos + 1; j < blockSize; j++ {
+			forged[j] = inter[j] ^ padding
+		}
+		found := false
+		for g := 0; g < 256; g++ {
+			forged[pos] = byte(g)
+			if !a.query(forged, block) {
+				continue
+			}
+			if pos == blockSize-1 {
+				// the plaintext may end with 02 02 instead of 01;
+				// changing the byte before must keep the padding valid
+				forged[pos-1] ^= 0xff
+				ok := a.query(forged, block)
+				forged[pos-1] ^= 0xff
+				if !ok {
+					continue
+				}
+			}
+			found = true
+			break
+		}
+		if !found {
+			return nil, errors.New("paddingoracle: oracle never reported valid padding")
+		}
+		inter[pos] = forged[pos] ^ padding
+		if a.Observe != nil {
+			a.Observe(Step{
+				Block:        n,
+				Index:        pos,
+				Intermediate: inter[pos],
+				Plaintext:    inter[pos] ^ prev[pos],
+				Queries:      a.queries,
+			})
+		}
+	}
+	return inter, nil
+}
+
+// Queries returns the number of oracle queries so far
+func (a *Attacker) Queries() int { return a.queries }
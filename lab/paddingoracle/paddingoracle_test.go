@@ -0,0 +1,80 @@
+package paddingoracle
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 40; n++ {
+		pt := make([]byte, n)
+		_, _ = rand.Read(pt)
+		ct, err := s.Encrypt(pt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Decrypt(ct)
+		if err != nil {
+			t.Fatalf("len=%d: %v", n, err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Fatalf("got=%X, want=%X", got, pt)
+		}
+	}
+	if _, err := s.Decrypt(make([]byte, 20)); !errors.Is(err, ErrLength) {
+		t.Errorf("err=%v, want %v", err, ErrLength)
+	}
+	if _, err := NewServer(Config{Key: make([]byte, 10)}); err == nil {
+		t.Error("10 bytes key is accepted")
+	}
+}
+
+func TestAttacker(t *testing.T) {
+	t.Parallel()
+
+	errFormat := errors.New("not a greeting")
+	s, err := NewServer(Config{
+		Validate: func(b []byte) error {
+			if !bytes.HasPrefix(b, []byte("hello")) {
+				return errFormat
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pt := range [][]byte{
+		[]byte("hello"),
+		[]byte("hello, this message spans several blocks of AES."),
+		// ends with bytes that look like padding
+		append([]byte("hello"), 0x02, 0x02),
+		append([]byte("hello"), bytes.Repeat([]byte{0x0b}, 11)...),
+	} {
+		ct, err := s.Encrypt(pt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps := 0
+		a := &Attacker{Oracle: s.Oracle, Observe: func(Step) { steps++ }}
+		got, err := a.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Fatalf("got=%q, want=%q", got, pt)
+		}
+		if want := len(ct) - blockSize; steps != want {
+			t.Errorf("observed %d steps, want %d", steps, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package paddingoracle is a lab for the CBC padding oracle attack.
+//
+// Server decrypts AES-CBC with toyAES and reports PKCS#7 padding errors
+// separately from other errors. Attacker uses only that difference to decrypt
+// any ciphertext produced by the server.
+//
+// See: S. Vaudenay, "Security Flaws Induced by CBC Padding"
+package paddingoracle
+
+import (
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"github.com/blck-snwmn/toyaes"
+	"github.com/blck-snwmn/toyaes/padding"
+)
+
+const blockSize = 16
+
+var (
+	// ErrPadding is returned by Server.Decrypt when the padding is invalid.
+	// Returning it separately is the vulnerability.
+	ErrPadding = errors.New("paddingoracle: invalid padding")
+	// ErrLength is returned when the ciphertext is not IV followed by whole blocks
+	ErrLength = errors.New("paddingoracle: invalid ciphertext length")
+)
+
+// Config configures a Server
+type Config struct {
+	// Key is the AES key. A random 128-bit key is used if nil.
+	Key []byte
+	// Validate is applied to the plaintext after the padding is removed,
+	// like an application parsing the message. Its error is returned as is.
+	Validate func(plaintext []byte) error
+}
+
+// Server holds the key and decrypts messages for clients
+type Server struct {
+	block    ccipher.Block
+	validate func([]byte) error
+	queries  int
+}
+
+// NewServer returns a Server configured by c
+func NewServer(c Config) (*Server, error) {
+	key := c.Key
+	if key == nil {
+		key = make([]byte, 16)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("paddingoracle: invalid key length")
+	}
+	validate := c.Validate
+	if validate == nil {
+		validate = func([]byte) error { return nil }
+	}
+	return &Server{block: toyaes.NewToyAES(key), validate: validate}, nil
+}
+
+// Encrypt pads plaintext by PKCS#7 and returns IV || ciphertext
+func (s *Server) Encrypt(plaintext []byte) ([]byte, error) {
+	padded := padding.PKCS7.Pad(plaintext, blockSize)
+	out := make([]byte, blockSize+len(padded))
+	iv := out[:blockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	ccipher.NewCBCEncrypter(s.block, iv).CryptBlocks(out[blockSize:], padded)
+	return out, nil
+}
+
+// Decrypt decrypts IV || ciphertext and removes the padding
+func (s *Server) Decrypt(ciphertext []byte) ([]byte, error) {
+	s.queries++
+	if len(ciphertext) < 2*blockSize || len(ciphertext)%blockSize != 0 {
+		return nil, ErrLength
+	}
+	iv, ct := ciphertext[:blockSize], ciphertext[blockSize:]
+	pt := make([]byte, len(ct))
+	ccipher.NewCBCDecrypter(s.block, iv).CryptBlocks(pt, ct)
+	pt, err := padding.PKCS7.Unpad(pt, blockSize)
+	if err != nil {
+		return nil, ErrPadding
+	}
+	if err := s.validate(pt); err != nil {
+		return nil, err
+	}
+	return pt, nil
+}
+
+// Queries returns the number of calls to Decrypt
+func (s *Server) Queries() int { return s.queries }
+
+// Oracle reports whether the padding of ciphertext is valid.
+// This is all an attacker learns from the error of Decrypt.
+func (s *Server) Oracle(ciphertext []byte) bool {
+	_, err := s.Decrypt(ciphertext)
+	return !errors.Is(err, ErrPadding)
+}
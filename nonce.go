@@ -0,0 +1,153 @@
+package toyaes
+
+import (
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// NonceSequence generates the deterministic nonces of SP 800-38D section 8.2.1.
+// A 12-byte nonce is the fixed field, which identifies the device or context,
+// followed by the invocation field, a big-endian counter starting from 0.
+// No nonce is generated twice; Next fails once the invocation field is exhausted.
+// It is safe for concurrent use.
+type NonceSequence struct {
+	mu     sync.Mutex
+	fixed  []byte
+	next   uint64
+	remain uint64
+}
+
+// ErrNonceExhausted is returned by NonceSequence.Next when all invocation
+// fields have been used
+var ErrNonceExhausted = errors.New("toyaes: nonce sequence exhausted")
+
+// NewNonceSequence returns a NonceSequence with the fixed field fixed,
+// which must be 4 to 11 bytes. The invocation field is the remaining
+// 8 to 1 bytes, so the sequence has 2^(8*(12-len(fixed))) nonces.
+func NewNonceSequence(fixed []byte) (*NonceSequence, error) {
+	if len(fixed) < 4 || len(fixed) >= gcmStandardNonceSize {
+		return nil, errors.New("toyaes: fixed field must be 4 to 11 bytes")
+	}
+	bits := 8 * (gcmStandardNonceSize - len(fixed))
+	// remain is the number of nonces minus 1 so that 2^64 nonces fit in uint64
+	remain := uint64(1)<<(bits-1)<<1 - 1
+	return &NonceSequence{fixed: append([]byte(nil), fixed...), remain: remain}, nil
+}
+
+// Next returns the next nonce, or ErrNonceExhausted if there is none.
+func (s *NonceSequence) Next() ([]byte, error) {
+	var nonce [gcmStandardNonceSize]byte
+	if err := s.fill(nonce[:]); err != nil {
+		return nil, err
+	}
+	return nonce[:], nil
+}
+
+// Remaining returns the number of nonces that Next can still return,
+// saturated at the maximum of uint64
+func (s *NonceSequence) Remaining() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fixed == nil {
+		return 0
+	}
+	if s.remain == ^uint64(0) {
+		return s.remain
+	}
+	return s.remain + 1
+}
+
+// fill writes the next nonce into nonce, which is 12 bytes
+func (s *NonceSequence) fill(nonce []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// fixed is cleared after the last nonce
+	if s.fixed == nil {
+		return ErrNonceExhausted
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], s.next)
+	copy(nonce, s.fixed)
+	copy(nonce[len(s.fixed):], counter[8-(gcmStandardNonceSize-len(s.fixed)):])
+	if s.remain == 0 {
+		s.fixed = nil
+	} else {
+		s.next++
+		s.remain--
+	}
+	return nil
+}
+
+var _ ccipher.AEAD = (*prefixNonceGCM)(nil)
+
+// prefixNonceGCM is GCM which makes the nonce in Seal and prefixes it to the ciphertext
+type prefixNonceGCM struct {
+	gcm *toyGCM
+	// nonce writes a new 12-byte nonce
+	nonce func(nonce []byte) error
+}
+
+// NewGCMWithRandomNonce returns GCM which generates a random 12-byte nonce
+// in Seal and prefixes it to the ciphertext, like cipher.NewGCMWithRandomNonce.
+// NonceSize is 0 and Overhead includes the nonce. The nonce argument of Seal
+// and Open must be empty.
+//
+// The probability of a repeated random nonce limits a key to about 2^32 messages.
+func NewGCMWithRandomNonce(cipher ccipher.Block, opts ...GCMOption) (ccipher.AEAD, error) {
+	return newPrefixNonceGCM(cipher, func(nonce []byte) error {
+		_, err := rand.Read(nonce)
+		return err
+	}, opts...)
+}
+
+// NewGCMWithNonceSequence is NewGCMWithRandomNonce which takes the nonces
+// from seq instead. Seal panics with ErrNonceExhausted once seq is exhausted;
+// check seq.Remaining beforehand to avoid it.
+func NewGCMWithNonceSequence(cipher ccipher.Block, seq *NonceSequence, opts ...GCMOption) (ccipher.AEAD, error) {
+	return newPrefixNonceGCM(cipher, seq.fill, opts...)
+}
+
+func newPrefixNonceGCM(cipher ccipher.Block, nonce func([]byte) error, opts ...GCMOption) (ccipher.AEAD, error) {
+	g, err := newGCM(cipher, gcmStandardNonceSize, gcmTagSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &prefixNonceGCM{gcm: g.(*toyGCM), nonce: nonce}, nil
+}
+
+// NonceSize implements cipher.AEAD
+func (*prefixNonceGCM) NonceSize() int { return 0 }
+
+// Overhead implements cipher.AEAD
+func (g *prefixNonceGCM) Overhead() int { return g.gcm.NonceSize() + g.gcm.Overhead() }
+
+// Seal implements cipher.AEAD
+func (g *prefixNonceGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("toyaes: non-empty nonce passed to GCM with generated nonces")
+	}
+	ret, prefix, ciphertext, in := sealShifted(dst, plaintext, additionalData, g.gcm.NonceSize(), g.Overhead())
+	if err := g.nonce(prefix); err != nil {
+		panic(err)
+	}
+	g.gcm.Seal(ciphertext[:0], prefix, in, additionalData)
+	return ret
+}
+
+// Open implements cipher.AEAD
+func (g *prefixNonceGCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != 0 {
+		return nil, NonceSizeError(len(nonce))
+	}
+	if len(ciphertext) < g.Overhead() {
+		return nil, ErrOpen
+	}
+	// the nonce may be overwritten by the plaintext in place
+	var n [gcmStandardNonceSize]byte
+	copy(n[:], ciphertext)
+	body := openShifted(dst, ciphertext, additionalData, len(n), len(ciphertext)-g.Overhead())
+	return g.gcm.Open(dst, n[:], body, additionalData)
+}
@@ -0,0 +1,193 @@
+package toyaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	ccipher "crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestNewGCMWithRandomNonce(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	aead, err := NewGCMWithRandomNonce(NewToyAES(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aead.NonceSize() != 0 || aead.Overhead() != 28 {
+		t.Fatalf("NonceSize() = %d, Overhead() = %d, want 0, 28", aead.NonceSize(), aead.Overhead())
+	}
+	plaintext := []byte("plaintext")
+	ad := []byte("additional data")
+
+	s1 := aead.Seal(nil, nil, plaintext, ad)
+	s2 := aead.Seal(nil, nil, plaintext, ad)
+	if bytes.Equal(s1[:12], s2[:12]) {
+		t.Error("the same nonce is generated twice")
+	}
+
+	// the prefixed nonce is that of GCM
+	want := NewGCM(NewToyAES(key)).Seal(nil, s1[:12], plaintext, ad)
+	if !bytes.Equal(s1[12:], want) {
+		t.Errorf("Seal = %X, want nonce followed by %X", s1, want)
+	}
+
+	// compatible with the standard library
+	block, _ := aes.NewCipher(key)
+	goAEAD, err := ccipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := goAEAD.Open(nil, nil, s1, ad); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Go Open = %X, %v, want %X", got, err, plaintext)
+	}
+	if got, err := aead.Open(nil, nil, goAEAD.Seal(nil, nil, plaintext, ad), ad); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open = %X, %v, want %X", got, err, plaintext)
+	}
+
+	for _, n := range []int{0, 12, 27} {
+		if _, err := aead.Open(nil, nil, s1[:n], ad); !errors.Is(err, ErrOpen) {
+			t.Errorf("ciphertext size %d: err = %v, want ErrOpen", n, err)
+		}
+	}
+	if _, err := aead.Open(nil, s1[:12], s1[12:], ad); err == nil {
+		t.Error("non-empty nonce is accepted")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Seal does not panic on non-empty nonce")
+		}
+	}()
+	aead.Seal(nil, make([]byte, 12), plaintext, ad)
+}
+
+func TestNewNonceSequence(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 3, 12, 16} {
+		if _, err := NewNonceSequence(make([]byte, n)); err == nil {
+			t.Errorf("fixed field of %d bytes is accepted", n)
+		}
+	}
+
+	fixed := []byte{0xde, 0xad, 0xbe, 0xef}
+	seq, err := NewNonceSequence(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seq.Remaining(); got != ^uint64(0) {
+		t.Errorf("Remaining() = %d, want %d", got, ^uint64(0))
+	}
+	fixed[0] = 0
+	for i := 0; i < 3; i++ {
+		nonce, err := seq.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0, 0, 0, 0, 0, byte(i)}
+		if !bytes.Equal(nonce, want) {
+			t.Errorf("Next() = %X, want %X", nonce, want)
+		}
+	}
+}
+
+func TestNonceSequence_Exhausted(t *testing.T) {
+	t.Parallel()
+
+	// the invocation field is 1 byte
+	seq, err := NewNonceSequence(make([]byte, 11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := NewGCMWithNonceSequence(NewToyAES(make([]byte, 16)), seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 256; i++ {
+		if got := seq.Remaining(); got != uint64(256-i) {
+			t.Fatalf("Remaining() = %d, want %d", got, 256-i)
+		}
+		sealed := aead.Seal(nil, nil, []byte("plaintext"), nil)
+		if seen[string(sealed[:12])] {
+			t.Fatalf("nonce %X is repeated", sealed[:12])
+		}
+		seen[string(sealed[:12])] = true
+		if _, err := aead.Open(nil, nil, sealed, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := seq.Remaining(); got != 0 {
+		t.Errorf("Remaining() = %d, want 0", got)
+	}
+	if _, err := seq.Next(); !errors.Is(err, ErrNonceExhausted) {
+		t.Errorf("err = %v, want ErrNonceExhausted", err)
+	}
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrNonceExhausted) {
+			t.Errorf("Seal panics with %v, want ErrNonceExhausted", err)
+		}
+	}()
+	aead.Seal(nil, nil, []byte("plaintext"), nil)
+}
+
+// testAEADInPlace checks Seal(buf[:0], nonce, buf, ad) and
+// Open(ct[:0], nonce, ct, ad) of an AEAD which prefixes its ciphertext
+func testAEADInPlace(t *testing.T, aead ccipher.AEAD, nonce []byte) {
+	t.Helper()
+
+	ad := []byte("additional data")
+	for _, size := range []int{0, 1, 11, 12, 13, 32, 33, 100, 1000} {
+		plaintext := make([]byte, size)
+		_, _ = rand.Read(plaintext)
+
+		buf := make([]byte, size, size+aead.Overhead())
+		copy(buf, plaintext)
+		sealed := aead.Seal(buf[:0], nonce, buf, ad)
+		if len(sealed) != size+aead.Overhead() || &sealed[0] != &buf[:1][0] {
+			t.Fatalf("size %d: in-place Seal does not reuse dst", size)
+		}
+		got, err := aead.Open(nil, nonce, sealed, ad)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("size %d: Open of in-place Seal = %X, %v, want %X", size, got, err, plaintext)
+		}
+
+		got, err = aead.Open(sealed[:0], nonce, sealed, ad)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("size %d: in-place Open = %X, %v, want %X", size, got, err, plaintext)
+		}
+		if size > 0 && &got[0] != &sealed[0] {
+			t.Fatalf("size %d: in-place Open does not reuse dst", size)
+		}
+	}
+
+	mustPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s does not panic", name)
+			}
+		}()
+		f()
+	}
+	buf := make([]byte, 200)
+	mustPanic("Seal with inexact overlap", func() { aead.Seal(buf[1:1], nonce, buf[:50], nil) })
+	mustPanic("Seal with output overlapping additional data", func() { aead.Seal(buf[:0], nonce, buf[:50], buf[60:70]) })
+	sealed := aead.Seal(nil, nonce, make([]byte, 50), nil)
+	copy(buf, sealed)
+	mustPanic("Open with inexact overlap", func() { _, _ = aead.Open(buf[1:1], nonce, buf[:len(sealed)], nil) })
+}
+
+func TestNewGCMWithRandomNonce_InPlace(t *testing.T) {
+	t.Parallel()
+
+	aead, err := NewGCMWithRandomNonce(NewToyAES(make([]byte, 16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAEADInPlace(t, aead, nil)
+}
@@ -0,0 +1,222 @@
+// Package padding implements padding schemes for block cipher modes.
+//
+// Unpad checks the last block in constant time: the error does not tell
+// which byte of the padding was wrong.
+package padding
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidPadding is wrapped by the error of Unpad when the padding is malformed
+var ErrInvalidPadding = errors.New("padding: invalid padding")
+
+// Scheme pads data to a multiple of the block size and removes the padding
+type Scheme interface {
+	// Pad returns a copy of b followed by the padding.
+	// It panics if blockSize is not supported by the scheme.
+	Pad(b []byte, blockSize int) []byte
+	// Unpad returns b without the padding. The result shares b.
+	Unpad(b []byte, blockSize int) ([]byte, error)
+	// String returns the name of the scheme
+	String() string
+}
+
+var (
+	// PKCS7 fills n bytes of the value n (RFC 5652 section 6.3)
+	PKCS7 Scheme = pkcs7{}
+	// ISO7816 appends 0x80 followed by zeros (ISO/IEC 7816-4, also known as ISO/IEC 9797-1 method 2)
+	ISO7816 Scheme = iso7816{}
+	// ANSIX923 fills zeros and the last byte is the padding length (ANSI X9.23)
+	ANSIX923 Scheme = ansiX923{}
+	// ISO10126 fills random bytes and the last byte is the padding length (ISO 10126)
+	ISO10126 Scheme = iso10126{}
+	// Zero fills zeros only if b is not a multiple of the block size.
+	// Unpad can not tell the padding from trailing zeros of the data.
+	Zero Scheme = zero{}
+)
+
+// padLen returns the number of padding bytes. At least one byte is added.
+func padLen(n, blockSize, maxBlockSize int) int {
+	if blockSize < 1 || blockSize > maxBlockSize {
+		panic(fmt.Sprintf("padding: invalid block size %d", blockSize))
+	}
+	return blockSize - n%blockSize
+}
+
+func appendPadding(b []byte, n int, fill func(p []byte)) []byte {
+	out := make([]byte, len(b)+n)
+	copy(out, b)
+	fill(out[len(b):])
+	return out
+}
+
+// lastBlock checks the length of b, which is not secret
+func lastBlock(s Scheme, b []byte, blockSize, maxBlockSize int) ([]byte, error) {
+	if blockSize < 1 || blockSize > maxBlockSize {
+		return nil, fmt.Errorf("padding: %s does not support block size %d", s, blockSize)
+	}
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, fmt.Errorf("padding: %s input length %d is not a positive multiple of block size %d", s, len(b), blockSize)
+	}
+	return b[len(b)-blockSize:], nil
+}
+
+func invalid(s Scheme) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPadding, s)
+}
+
+// checkLengthByte checks the last byte n of block as 1 <= n <= len(block)
+// and, if zeros is 1, that the n-1 bytes before it are zero.
+// It returns n and 1 if the padding is valid.
+func checkLengthByte(block []byte, zeros int, pkcs7 int) (int, int) {
+	bs := len(block)
+	last := block[bs-1]
+	n := int(last)
+	good := subtle.ConstantTimeLessOrEq(1, n) & subtle.ConstantTimeLessOrEq(n, bs)
+	for i := 1; i < bs; i++ {
+		b := block[bs-1-i]
+		inPad := subtle.ConstantTimeLessOrEq(i+1, n)
+		ok := subtle.ConstantTimeSelect(zeros, subtle.ConstantTimeByteEq(b, 0), 1)
+		ok = subtle.ConstantTimeSelect(pkcs7, subtle.ConstantTimeByteEq(b, last), ok)
+		good &= subtle.ConstantTimeSelect(inPad, ok, 1)
+	}
+	return n, good
+}
+
+// trailingZeros returns the number of zeros at the end of block and the
+// byte before them, or 0 if block is all zeros.
+func trailingZeros(block []byte) (int, byte) {
+	bs := len(block)
+	found, pos, marker := 0, bs, 0
+	for i := 0; i < bs; i++ {
+		b := block[bs-1-i]
+		take := (1 - found) & (1 - subtle.ConstantTimeByteEq(b, 0))
+		pos = subtle.ConstantTimeSelect(take, i, pos)
+		marker = subtle.ConstantTimeSelect(take, int(b), marker)
+		found |= take
+	}
+	return pos, byte(marker)
+}
+
+type pkcs7 struct{}
+
+func (pkcs7) String() string { return "PKCS#7" }
+
+func (pkcs7) Pad(b []byte, blockSize int) []byte {
+	n := padLen(len(b), blockSize, 255)
+	return appendPadding(b, n, func(p []byte) {
+		for i := range p {
+			p[i] = byte(n)
+		}
+	})
+}
+
+func (s pkcs7) Unpad(b []byte, blockSize int) ([]byte, error) {
+	block, err := lastBlock(s, b, blockSize, 255)
+	if err != nil {
+		return nil, err
+	}
+	n, good := checkLengthByte(block, 0, 1)
+	if good != 1 {
+		return nil, invalid(s)
+	}
+	return b[:len(b)-n], nil
+}
+
+type ansiX923 struct{}
+
+func (ansiX923) String() string { return "ANSI X9.23" }
+
+func (ansiX923) Pad(b []byte, blockSize int) []byte {
+	n := padLen(len(b), blockSize, 255)
+	return appendPadding(b, n, func(p []byte) {
+		p[len(p)-1] = byte(n)
+	})
+}
+
+func (s ansiX923) Unpad(b []byte, blockSize int) ([]byte, error) {
+	block, err := lastBlock(s, b, blockSize, 255)
+	if err != nil {
+		return nil, err
+	}
+	n, good := checkLengthByte(block, 1, 0)
+	if good != 1 {
+		return nil, invalid(s)
+	}
+	return b[:len(b)-n], nil
+}
+
+type iso10126 struct{}
+
+func (iso10126) String() string { return "ISO 10126" }
+
+func (iso10126) Pad(b []byte, blockSize int) []byte {
+	n := padLen(len(b), blockSize, 255)
+	return appendPadding(b, n, func(p []byte) {
+		_, _ = rand.Read(p[:len(p)-1])
+		p[len(p)-1] = byte(n)
+	})
+}
+
+func (s iso10126) Unpad(b []byte, blockSize int) ([]byte, error) {
+	block, err := lastBlock(s, b, blockSize, 255)
+	if err != nil {
+		return nil, err
+	}
+	n, good := checkLengthByte(block, 0, 0)
+	if good != 1 {
+		return nil, invalid(s)
+	}
+	return b[:len(b)-n], nil
+}
+
+type iso7816 struct{}
+
+func (iso7816) String() string { return "ISO/IEC 7816-4" }
+
+func (iso7816) Pad(b []byte, blockSize int) []byte {
+	n := padLen(len(b), blockSize, math.MaxInt)
+	return appendPadding(b, n, func(p []byte) {
+		p[0] = 0x80
+	})
+}
+
+func (s iso7816) Unpad(b []byte, blockSize int) ([]byte, error) {
+	block, err := lastBlock(s, b, blockSize, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	zeros, marker := trailingZeros(block)
+	if subtle.ConstantTimeByteEq(marker, 0x80) != 1 {
+		return nil, invalid(s)
+	}
+	return b[:len(b)-zeros-1], nil
+}
+
+type zero struct{}
+
+func (zero) String() string { return "zero" }
+
+func (zero) Pad(b []byte, blockSize int) []byte {
+	n := padLen(len(b), blockSize, math.MaxInt) % blockSize
+	return appendPadding(b, n, func([]byte) {})
+}
+
+func (s zero) Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 && blockSize > 0 {
+		return b, nil
+	}
+	block, err := lastBlock(s, b, blockSize, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	zeros, _ := trailingZeros(block)
+	// Pad adds blockSize-1 zeros at most
+	zeros = subtle.ConstantTimeSelect(subtle.ConstantTimeEq(int32(zeros), int32(blockSize)), blockSize-1, zeros)
+	return b[:len(b)-zeros], nil
+}
@@ -0,0 +1,119 @@
+package padding
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPad(t *testing.T) {
+	t.Parallel()
+
+	in := []byte("abcdefghijklm") // 13 bytes
+	tests := []struct {
+		scheme Scheme
+		want   []byte
+	}{
+		{PKCS7, append([]byte("abcdefghijklm"), 0x03, 0x03, 0x03)},
+		{ISO7816, append([]byte("abcdefghijklm"), 0x80, 0x00, 0x00)},
+		{ANSIX923, append([]byte("abcdefghijklm"), 0x00, 0x00, 0x03)},
+		{Zero, append([]byte("abcdefghijklm"), 0x00, 0x00, 0x00)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme.String(), func(t *testing.T) {
+			if got := tt.scheme.Pad(in, 16); !bytes.Equal(got, tt.want) {
+				t.Errorf("Pad() = %X, want %X", got, tt.want)
+			}
+		})
+	}
+
+	got := ISO10126.Pad(in, 16)
+	if len(got) != 16 || got[15] != 0x03 || !bytes.Equal(got[:13], in) {
+		t.Errorf("ISO10126.Pad() = %X", got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []Scheme{PKCS7, ISO7816, ANSIX923, ISO10126, Zero} {
+		t.Run(s.String(), func(t *testing.T) {
+			for _, bs := range []int{8, 16} {
+				for n := 0; n <= 3*bs; n++ {
+					in := bytes.Repeat([]byte{0xa5}, n)
+					p := s.Pad(in, bs)
+					if len(p)%bs != 0 || len(p) < n {
+						t.Fatalf("block size %d: len(Pad(%d bytes)) = %d", bs, n, len(p))
+					}
+					got, err := s.Unpad(p, bs)
+					if err != nil {
+						t.Fatalf("block size %d: Unpad(Pad(%d bytes)): %v", bs, n, err)
+					}
+					if !bytes.Equal(got, in) {
+						t.Fatalf("block size %d: Unpad(Pad(%X)) = %X", bs, in, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUnpad_invalid(t *testing.T) {
+	t.Parallel()
+
+	block := func(tail ...byte) []byte {
+		return append(bytes.Repeat([]byte{0x41}, 16-len(tail)), tail...)
+	}
+	tests := []struct {
+		name   string
+		scheme Scheme
+		in     []byte
+	}{
+		{"PKCS7 zero", PKCS7, block(0x00)},
+		{"PKCS7 too large", PKCS7, block(0x11)},
+		{"PKCS7 mismatch", PKCS7, block(0x03, 0x02, 0x03)},
+		{"PKCS7 not a block", PKCS7, []byte{0x01}},
+		{"PKCS7 empty", PKCS7, nil},
+		{"ANSIX923 nonzero", ANSIX923, block(0x00, 0x01, 0x03)},
+		{"ANSIX923 zero", ANSIX923, block(0x00)},
+		{"ISO10126 too large", ISO10126, block(0x20)},
+		{"ISO7816 no marker", ISO7816, block(0x00)},
+		{"ISO7816 wrong marker", ISO7816, block(0x81, 0x00)},
+		{"ISO7816 all zero", ISO7816, make([]byte, 16)},
+		{"Zero not a block", Zero, []byte{0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.scheme.Unpad(tt.in, 16); err == nil {
+				t.Errorf("Unpad(%X) = %X, want error", tt.in, got)
+			}
+		})
+	}
+
+	_, err := PKCS7.Unpad(block(0x00), 16)
+	if !errors.Is(err, ErrInvalidPadding) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidPadding)
+	}
+	_, err = PKCS7.Unpad([]byte{0x01}, 16)
+	if errors.Is(err, ErrInvalidPadding) {
+		t.Errorf("length error %v wraps %v", err, ErrInvalidPadding)
+	}
+}
+
+func TestPad_invalidBlockSize(t *testing.T) {
+	t.Parallel()
+
+	for _, bs := range []int{0, 256} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("PKCS7.Pad with block size %d does not panic", bs)
+				}
+			}()
+			PKCS7.Pad(nil, bs)
+		}()
+	}
+	if _, err := PKCS7.Unpad(make([]byte, 256), 256); err == nil {
+		t.Error("PKCS7.Unpad accepts block size 256")
+	}
+}
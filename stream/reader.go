@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+type decryptingReader struct {
+	r    io.Reader
+	seg  *segmenter
+	conf config
+	// ct is the ciphertext read ahead. One byte beyond a segment is read
+	// to know whether the segment is the last.
+	ct []byte
+	// pt is the plaintext not read yet
+	pt  []byte
+	buf []byte
+	i   uint64
+	// err is io.EOF after the last segment
+	err error
+}
+
+// NewDecryptingReader reads the header from r and returns a Reader which
+// decrypts the stream written by NewEncryptingWriter with the same key,
+// associatedData and options.
+//
+// Read returns the plaintext of a segment only after its tag is verified.
+// A modified, reordered or truncated stream makes Read fail with an error
+// wrapping toyaes.ErrOpen, so the data read before it must be discarded
+// unless partial plaintext is acceptable.
+func NewDecryptingReader(r io.Reader, key, associatedData []byte, opts ...Option) (io.Reader, error) {
+	conf, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %w", ErrHeader, err)
+		}
+		return nil, err
+	}
+	seg, err := newSegmenter(key, associatedData, header)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{
+		r:    r,
+		seg:  seg,
+		conf: conf,
+		ct:   make([]byte, 0, conf.segmentSize+1),
+		buf:  make([]byte, 0, conf.plaintextSize()),
+	}, nil
+}
+
+// Read implements io.Reader
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.pt) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.pt)
+	d.pt = d.pt[n:]
+	return n, nil
+}
+
+// next decrypts the next segment into pt. It returns io.EOF after the last segment.
+func (d *decryptingReader) next() error {
+	if d.i >= maxSegments {
+		return ErrTooManySegments
+	}
+	n, err := io.ReadFull(d.r, d.ct[len(d.ct):cap(d.ct)])
+	d.ct = d.ct[:len(d.ct)+n]
+	last := false
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	default:
+		return err
+	}
+
+	segment := d.ct
+	if !last {
+		segment = d.ct[:d.conf.segmentSize]
+	}
+	pt, err := d.seg.open(d.buf[:0], uint32(d.i), last, segment)
+	if err != nil {
+		return err
+	}
+	d.pt = pt
+	d.i++
+	if last {
+		return io.EOF
+	}
+	// keep the byte read ahead
+	d.ct[0] = d.ct[d.conf.segmentSize]
+	d.ct = d.ct[:1]
+	return nil
+}
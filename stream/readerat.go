@@ -0,0 +1,131 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+// ReaderAt decrypts any range of a stream written by NewEncryptingWriter.
+// ReadAt reads and verifies only the segments covering the range.
+// It is safe for concurrent use if the underlying io.ReaderAt is.
+type ReaderAt struct {
+	r    io.ReaderAt
+	seg  *segmenter
+	conf config
+	// segments is the number of segments and lastSize is the size of the last one
+	segments int64
+	lastSize int
+}
+
+var _ io.ReaderAt = (*ReaderAt)(nil)
+
+// NewDecryptingReaderAt returns a ReaderAt over the stream of size bytes in r,
+// with the key, associatedData and options given to NewEncryptingWriter.
+//
+// The segment boundaries follow from size, and the last segment is verified
+// here, so truncation at a segment boundary is detected before any read and
+// Size is authenticated.
+func NewDecryptingReaderAt(r io.ReaderAt, size int64, key, associatedData []byte, opts ...Option) (*ReaderAt, error) {
+	conf, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, HeaderSize)
+	if err := readFullAt(r, header, 0); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %w", ErrHeader, err)
+		}
+		return nil, err
+	}
+	seg, err := newSegmenter(key, associatedData, header)
+	if err != nil {
+		return nil, err
+	}
+
+	body := size - HeaderSize
+	segSize := int64(conf.segmentSize)
+	// an empty stream still has the last segment, which is only the tag
+	segments := max((body+segSize-1)/segSize, 1)
+	if segments > maxSegments {
+		return nil, ErrTooManySegments
+	}
+	ra := &ReaderAt{
+		r:        r,
+		seg:      seg,
+		conf:     conf,
+		segments: segments,
+		lastSize: int(body - (segments-1)*segSize),
+	}
+	if ra.lastSize < tagSize {
+		return nil, fmt.Errorf("stream: last segment of %d bytes: %w", max(ra.lastSize, 0), toyaes.ErrOpen)
+	}
+	if _, err := ra.segment(nil, make([]byte, conf.segmentSize), segments-1); err != nil {
+		return nil, err
+	}
+	return ra, nil
+}
+
+// Size returns the size of the plaintext
+func (ra *ReaderAt) Size() int64 {
+	return (ra.segments-1)*int64(ra.conf.plaintextSize()) + int64(ra.lastSize-tagSize)
+}
+
+// ReadAt implements io.ReaderAt. It fails with an error wrapping
+// toyaes.ErrOpen if a segment covering the range is not authentic.
+func (ra *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("stream: negative offset")
+	}
+	size := ra.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+	ptSize := int64(ra.conf.plaintextSize())
+	ct := make([]byte, ra.conf.segmentSize)
+	pt := make([]byte, 0, ptSize)
+
+	var n int
+	for n < len(p) && off < size {
+		i := off / ptSize
+		plain, err := ra.segment(pt, ct, i)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], plain[off-i*ptSize:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// segment reads and decrypts the i-th segment into dst[:0] using ct as the buffer
+func (ra *ReaderAt) segment(dst, ct []byte, i int64) ([]byte, error) {
+	last := i == ra.segments-1
+	ct = ct[:ra.conf.segmentSize]
+	if last {
+		ct = ct[:ra.lastSize]
+	}
+	if err := readFullAt(ra.r, ct, HeaderSize+i*int64(ra.conf.segmentSize)); err != nil {
+		return nil, err
+	}
+	return ra.seg.open(dst[:0], uint32(i), last, ct)
+}
+
+// readFullAt reads exactly len(b) bytes at off. io.ReaderAt may return
+// io.EOF with all the bytes at the end of the input.
+func readFullAt(r io.ReaderAt, b []byte, off int64) error {
+	n, err := r.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+func newTestReaderAt(key, ad, ciphertext []byte) (*ReaderAt, error) {
+	return NewDecryptingReaderAt(bytes.NewReader(ciphertext), int64(len(ciphertext)), key, ad, WithSegmentSize(testSegmentSize))
+}
+
+func TestReaderAt(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	ad := []byte("associated data")
+
+	for _, size := range []int{0, 1, testPlaintextSize, testPlaintextSize + 1, 5*testPlaintextSize + 3} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			t.Parallel()
+
+			plaintext := make([]byte, size)
+			_, _ = rand.Read(plaintext)
+			ra, err := newTestReaderAt(key, ad, encrypt(t, key, ad, plaintext, size+1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ra.Size() != int64(size) {
+				t.Fatalf("Size() = %d, want %d", ra.Size(), size)
+			}
+			// iotest.TestReader checks ReadAt at various offsets as well
+			if err := iotest.TestReader(io.NewSectionReader(ra, 0, ra.Size()), plaintext); err != nil {
+				t.Fatal(err)
+			}
+
+			for off := 0; off <= size; off++ {
+				for _, n := range []int{0, 1, testPlaintextSize - 1, testPlaintextSize + 2, size + 1} {
+					p := make([]byte, n)
+					got, err := ra.ReadAt(p, int64(off))
+					want := min(n, size-off)
+					if got != want || !bytes.Equal(p[:got], plaintext[off:off+want]) {
+						t.Fatalf("ReadAt(%d bytes, %d) = %d, want %d", n, off, got, want)
+					}
+					if want < n && err != io.EOF {
+						t.Fatalf("ReadAt(%d bytes, %d): err = %v, want io.EOF", n, off, err)
+					}
+					// io.EOF is allowed for an empty read at the end
+					if want == n && err != nil && (n > 0 || err != io.EOF) {
+						t.Fatalf("ReadAt(%d bytes, %d): %v", n, off, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReaderAt_Reject(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	plaintext := make([]byte, 4*testPlaintextSize)
+	_, _ = rand.Read(plaintext)
+	ct := encrypt(t, key, nil, plaintext, len(plaintext))
+
+	// truncation is detected by NewDecryptingReaderAt
+	for _, n := range []int{HeaderSize, HeaderSize + 15, HeaderSize + 2*testSegmentSize, len(ct) - 1} {
+		if _, err := newTestReaderAt(key, nil, ct[:n]); !errors.Is(err, toyaes.ErrOpen) {
+			t.Errorf("truncated to %d bytes: err = %v, want ErrOpen", n, err)
+		}
+	}
+	if _, err := newTestReaderAt(key, nil, ct[:HeaderSize-1]); !errors.Is(err, ErrHeader) {
+		t.Errorf("truncated header: err = %v, want ErrHeader", err)
+	}
+
+	// a modified segment fails only the reads covering it
+	modified := bytes.Clone(ct)
+	modified[HeaderSize+testSegmentSize+3] ^= 1
+	ra, err := newTestReaderAt(key, nil, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, testPlaintextSize)
+	if _, err := ra.ReadAt(p, 0); err != nil {
+		t.Errorf("segment 0: %v", err)
+	}
+	if _, err := ra.ReadAt(p, 2*testPlaintextSize); err != nil {
+		t.Errorf("segment 2: %v", err)
+	}
+	if _, err := ra.ReadAt(p, testPlaintextSize+10); !errors.Is(err, toyaes.ErrOpen) {
+		t.Errorf("segment 1: err = %v, want ErrOpen", err)
+	}
+	if _, err := ra.ReadAt(p, testPlaintextSize-1); !errors.Is(err, toyaes.ErrOpen) {
+		t.Errorf("segments 0 and 1: err = %v, want ErrOpen", err)
+	}
+
+	// swapped segments of the same size fail
+	swapped := bytes.Clone(ct)
+	s0 := swapped[HeaderSize : HeaderSize+testSegmentSize]
+	s1 := swapped[HeaderSize+testSegmentSize : HeaderSize+2*testSegmentSize]
+	tmp := bytes.Clone(s0)
+	copy(s0, s1)
+	copy(s1, tmp)
+	if ra, err = newTestReaderAt(key, nil, swapped); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ra.ReadAt(p, 0); !errors.Is(err, toyaes.ErrOpen) {
+		t.Errorf("swapped: err = %v, want ErrOpen", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package stream implements segmented streaming AEAD on toyaes GCM, following
+// the STREAM construction and the design of Tink's AES-GCM-HKDF StreamingAead.
+//
+// A stream is a header followed by segments:
+//
+//	header  = headerSize || salt || noncePrefix
+//	segment = GCM(segmentKey, noncePrefix || [i]32 || last, plaintext_i)
+//
+// The segment key is HKDF-SHA256(key, salt, associatedData), so each stream
+// has its own key. Every segment but the last holds SegmentSize-16 bytes of
+// plaintext. The segment number and the last flag in the nonce make any
+// reordering, removal or truncation of segments fail authentication.
+// Because the segments are independent, NewDecryptingReaderAt can decrypt
+// any range of a stream by reading only the segments covering it.
+//
+// See: V. T. Hoang, R. Reyhanitabar, P. Rogaway and D. Vizár,
+// "Online Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance"
+package stream
+
+import (
+	ccipher "crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+const (
+	// NoncePrefixSize is the size of the random nonce prefix in the header
+	NoncePrefixSize = 7
+	// SaltSize is the size of the random HKDF salt in the header
+	SaltSize = 32
+	// HeaderSize is the size of the header
+	HeaderSize = 1 + SaltSize + NoncePrefixSize
+	// DefaultSegmentSize is the segment size without WithSegmentSize
+	DefaultSegmentSize = 1 << 16
+
+	tagSize = 16
+	// maxSegments is the number of segment numbers of the 32-bit counter
+	maxSegments = math.MaxUint32 + 1
+)
+
+var (
+	// ErrHeader is returned for a header which is not of this package
+	ErrHeader = errors.New("stream: invalid header")
+	// ErrTooManySegments is returned when the stream needs more than 2^32 segments
+	ErrTooManySegments = errors.New("stream: too many segments")
+)
+
+// Option configures the segmentation of a stream.
+// The same options must be given to encryption and decryption.
+type Option func(*config)
+
+type config struct {
+	segmentSize int
+}
+
+// WithSegmentSize sets the size of an encrypted segment including its 16-byte tag.
+// The default is DefaultSegmentSize.
+func WithSegmentSize(n int) Option {
+	return func(c *config) { c.segmentSize = n }
+}
+
+func newConfig(opts []Option) (config, error) {
+	c := config{segmentSize: DefaultSegmentSize}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.segmentSize <= tagSize {
+		return c, fmt.Errorf("stream: segment size %d is not larger than the tag", c.segmentSize)
+	}
+	return c, nil
+}
+
+// plaintextSize is the size of the plaintext of a segment but the last
+func (c config) plaintextSize() int { return c.segmentSize - tagSize }
+
+// segmenter seals and opens the segments of a stream
+type segmenter struct {
+	aead   ccipher.AEAD
+	prefix [NoncePrefixSize]byte
+}
+
+// newSegmenter derives the segment key from key, which is 16, 24 or 32 bytes,
+// and the header
+func newSegmenter(key, associatedData, header []byte) (*segmenter, error) {
+	if len(header) != HeaderSize || header[0] != HeaderSize {
+		return nil, ErrHeader
+	}
+	if _, err := toyaes.NewToyAESWithError(key); err != nil {
+		return nil, err
+	}
+	salt := header[1 : 1+SaltSize]
+	segmentKey, err := hkdf.Key(sha256.New, key, salt, string(associatedData), len(key))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := toyaes.NewGCMWithError(toyaes.NewToyAES(segmentKey))
+	if err != nil {
+		return nil, err
+	}
+	s := &segmenter{aead: aead}
+	copy(s.prefix[:], header[1+SaltSize:])
+	return s, nil
+}
+
+// nonce returns the nonce of the i-th segment
+func (s *segmenter) nonce(i uint32, last bool) []byte {
+	var nonce [NoncePrefixSize + 4 + 1]byte
+	copy(nonce[:], s.prefix[:])
+	binary.BigEndian.PutUint32(nonce[NoncePrefixSize:], i)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce[:]
+}
+
+func (s *segmenter) seal(dst []byte, i uint32, last bool, plaintext []byte) []byte {
+	return s.aead.Seal(dst, s.nonce(i, last), plaintext, nil)
+}
+
+func (s *segmenter) open(dst []byte, i uint32, last bool, ciphertext []byte) ([]byte, error) {
+	p, err := s.aead.Open(dst, s.nonce(i, last), ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("stream: segment %d: %w", i, err)
+	}
+	return p, nil
+}
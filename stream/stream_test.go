@@ -0,0 +1,189 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/blck-snwmn/toyaes"
+)
+
+const testSegmentSize = 48
+
+// plaintext size of a segment with testSegmentSize
+const testPlaintextSize = testSegmentSize - 16
+
+func encrypt(t *testing.T, key, ad, plaintext []byte, chunk int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewEncryptingWriter(&buf, key, ad, WithSegmentSize(testSegmentSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := plaintext; len(p) > 0; {
+		n := min(chunk, len(p))
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(key, ad, ciphertext []byte) ([]byte, error) {
+	r, err := NewDecryptingReader(bytes.NewReader(ciphertext), key, ad, WithSegmentSize(testSegmentSize))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	ad := []byte("associated data")
+
+	for _, size := range []int{0, 1, testPlaintextSize - 1, testPlaintextSize, testPlaintextSize + 1, 3 * testPlaintextSize, 3*testPlaintextSize + 5} {
+		for _, chunk := range []int{1, 7, testPlaintextSize, 1000} {
+			t.Run(fmt.Sprintf("size=%d,chunk=%d", size, chunk), func(t *testing.T) {
+				t.Parallel()
+
+				plaintext := make([]byte, size)
+				_, _ = rand.Read(plaintext)
+				ct := encrypt(t, key, ad, plaintext, chunk)
+
+				segments := max((size+testPlaintextSize-1)/testPlaintextSize, 1)
+				if want := HeaderSize + size + 16*segments; len(ct) != want {
+					t.Errorf("len(ciphertext) = %d, want %d", len(ct), want)
+				}
+				got, err := decrypt(key, ad, ct)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, plaintext) {
+					t.Errorf("decrypted = %X, want %X", got, plaintext)
+				}
+			})
+		}
+	}
+}
+
+func TestStream_Reject(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	ad := []byte("associated data")
+	plaintext := make([]byte, 3*testPlaintextSize+5)
+	_, _ = rand.Read(plaintext)
+	ct := encrypt(t, key, ad, plaintext, len(plaintext))
+
+	segment := func(i int) []byte {
+		start := HeaderSize + i*testSegmentSize
+		return ct[start:min(start+testSegmentSize, len(ct))]
+	}
+	concat := func(b ...[]byte) []byte { return bytes.Join(b, nil) }
+	header := ct[:HeaderSize]
+
+	tests := []struct {
+		name       string
+		key        []byte
+		ad         []byte
+		ciphertext []byte
+	}{
+		{name: "truncated at a segment boundary", ciphertext: ct[:HeaderSize+2*testSegmentSize]},
+		{name: "truncated in a segment", ciphertext: ct[:len(ct)-1]},
+		{name: "header only", ciphertext: header},
+		{name: "segment removed", ciphertext: concat(header, segment(0), segment(2), segment(3))},
+		{name: "segments swapped", ciphertext: concat(header, segment(1), segment(0), segment(2), segment(3))},
+		{name: "last segment duplicated", ciphertext: concat(ct, segment(3))},
+		{name: "extended", ciphertext: concat(ct, []byte{0})},
+		{name: "salt modified", ciphertext: concat([]byte{HeaderSize, ct[1] ^ 1}, ct[2:])},
+		{name: "nonce prefix modified", ciphertext: concat(ct[:HeaderSize-1], []byte{ct[HeaderSize-1] ^ 1}, ct[HeaderSize:])},
+		{name: "wrong associated data", ad: []byte("other"), ciphertext: ct},
+		{name: "wrong key", key: make([]byte, 32), ciphertext: ct},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			k, a := key, ad
+			if tt.key != nil {
+				k = tt.key
+			}
+			if tt.ad != nil {
+				a = tt.ad
+			}
+			if _, err := decrypt(k, a, tt.ciphertext); !errors.Is(err, toyaes.ErrOpen) {
+				t.Errorf("err = %v, want ErrOpen", err)
+			}
+		})
+	}
+
+	for i := range ct {
+		modified := bytes.Clone(ct)
+		modified[i] ^= 0x80
+		if _, err := decrypt(key, ad, modified); err == nil {
+			t.Errorf("ciphertext modified at %d is accepted", i)
+		}
+	}
+}
+
+func TestStream_Header(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	for _, header := range [][]byte{nil, make([]byte, HeaderSize-1), make([]byte, HeaderSize)} {
+		if _, err := NewDecryptingReader(bytes.NewReader(header), key, nil); !errors.Is(err, ErrHeader) {
+			t.Errorf("header %X: err = %v, want ErrHeader", header, err)
+		}
+	}
+	if _, err := NewEncryptingWriter(io.Discard, make([]byte, 20), nil); err == nil {
+		t.Error("20-byte key is accepted")
+	}
+	if _, err := NewEncryptingWriter(io.Discard, key, nil, WithSegmentSize(16)); err == nil {
+		t.Error("segment size 16 is accepted")
+	}
+}
+
+func TestEncryptingWriter_Close(t *testing.T) {
+	t.Parallel()
+
+	w, err := NewEncryptingWriter(io.Discard, make([]byte, 16), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := w.Write([]byte{0}); err == nil {
+		t.Error("Write after Close succeeds")
+	}
+}
+
+func Example() {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+
+	var encrypted bytes.Buffer
+	w, _ := NewEncryptingWriter(&encrypted, key, []byte("example.txt"))
+	_, _ = io.WriteString(w, "segmented streaming AEAD\n")
+	_ = w.Close()
+
+	r, _ := NewDecryptingReader(&encrypted, key, []byte("example.txt"))
+	_, _ = io.Copy(os.Stdout, r)
+	// Output: segmented streaming AEAD
+}
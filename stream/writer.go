@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+var errClosed = errors.New("stream: write to closed writer")
+
+type encryptingWriter struct {
+	w    io.Writer
+	seg  *segmenter
+	conf config
+	// buf is the plaintext of the current segment. A full buf is sealed
+	// only when more data is written, because the last segment is not
+	// known until Close.
+	buf []byte
+	out []byte
+	i   uint64
+	err error
+}
+
+// NewEncryptingWriter writes the header to w and returns a WriteCloser
+// which encrypts the data written to it into w. Close must be called
+// to write the last segment; otherwise the stream is truncated.
+//
+// key is 16, 24 or 32 bytes. associatedData is authenticated but not
+// encrypted, and must be given to NewDecryptingReader as well.
+func NewEncryptingWriter(w io.Writer, key, associatedData []byte, opts ...Option) (io.WriteCloser, error) {
+	conf, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, HeaderSize)
+	header[0] = HeaderSize
+	if _, err := rand.Read(header[1:]); err != nil {
+		return nil, err
+	}
+	seg, err := newSegmenter(key, associatedData, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{
+		w:    w,
+		seg:  seg,
+		conf: conf,
+		buf:  make([]byte, 0, conf.plaintextSize()),
+		out:  make([]byte, 0, conf.segmentSize),
+	}, nil
+}
+
+// Write implements io.Writer
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	var n int
+	for len(p) > 0 {
+		if len(e.buf) == cap(e.buf) {
+			if err := e.flush(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Close writes the last segment. It does not close the underlying writer.
+func (e *encryptingWriter) Close() error {
+	if e.err != nil {
+		if errors.Is(e.err, errClosed) {
+			return nil
+		}
+		return e.err
+	}
+	if err := e.flush(true); err != nil {
+		return err
+	}
+	e.err = errClosed
+	return nil
+}
+
+// flush seals buf as the next segment and writes it
+func (e *encryptingWriter) flush(last bool) error {
+	if e.i >= maxSegments {
+		e.err = ErrTooManySegments
+		return e.err
+	}
+	e.out = e.seg.seal(e.out[:0], uint32(e.i), last, e.buf)
+	if _, err := e.w.Write(e.out); err != nil {
+		e.err = err
+		return err
+	}
+	e.i++
+	e.buf = e.buf[:0]
+	return nil
+}
@@ -3,6 +3,7 @@ package toyaes
 import (
 	"encoding/binary"
 	"fmt"
+	"math/bits"
 )
 
 func pubUint128(b []byte, v uint128) {
@@ -53,6 +54,14 @@ func (u uint128) and(other uint128) uint128 {
 	}
 }
 
+// reverse reverses the order of all 128 bits
+func (u uint128) reverse() uint128 {
+	return uint128{
+		lhs: bits.Reverse64(u.rhs),
+		rhs: bits.Reverse64(u.lhs),
+	}
+}
+
 // func (u uint128) add(other uint128) uint128 {
 // 	lhs := u.lhs + other.lhs
 // 	rhs := u.rhs + other.rhs
@@ -0,0 +1,100 @@
+package toyaes
+
+import (
+	"errors"
+	"sync"
+)
+
+// gcmMaxPlaintext is the maximum plaintext length of a message, 2^39-256 bits
+// (SP 800-38D section 5.2.1.1). Beyond it the 32-bit counter would wrap.
+const gcmMaxPlaintext = 1<<36 - 32
+
+// RandomNonceMaxSeals is the limit of Seal calls per key with random 12-byte
+// nonces (SP 800-38D section 8.3)
+const RandomNonceMaxSeals = 1 << 32
+
+// ErrUsageLimit is returned by the policy of SealLimit when a limit is reached
+var ErrUsageLimit = errors.New("toyaes: usage limit of the key reached")
+
+// Usage is the accounting of a GCM key
+type Usage struct {
+	// Seals is the number of Seal calls and SealedBytes is the sum of their plaintext lengths
+	Seals       uint64
+	SealedBytes uint64
+	// Opens is the number of Open calls, OpenFailures is the number of them
+	// that failed, and OpenedBytes is the sum of the plaintext lengths of the
+	// successful ones
+	Opens        uint64
+	OpenFailures uint64
+	OpenedBytes  uint64
+}
+
+// UsagePolicy decides whether Seal may encrypt n more bytes with a key used as u.
+// A non-nil error makes Seal panic with it.
+type UsagePolicy func(u Usage, n int) error
+
+// SealLimit returns a UsagePolicy that allows at most maxSeals Seal calls
+// and maxBytes plaintext bytes in total. 0 means no limit.
+func SealLimit(maxSeals, maxBytes uint64) UsagePolicy {
+	return func(u Usage, n int) error {
+		if maxSeals != 0 && u.Seals >= maxSeals {
+			return ErrUsageLimit
+		}
+		if maxBytes != 0 && (u.SealedBytes > maxBytes || uint64(n) > maxBytes-u.SealedBytes) {
+			return ErrUsageLimit
+		}
+		return nil
+	}
+}
+
+// UsageTracker counts the use of a GCM key given by WithUsageTracker.
+// It is safe for concurrent use.
+type UsageTracker struct {
+	mu     sync.Mutex
+	usage  Usage
+	policy UsagePolicy
+}
+
+// NewUsageTracker returns a UsageTracker checking Seal by policy, which may be nil
+func NewUsageTracker(policy UsagePolicy) *UsageTracker {
+	return &UsageTracker{policy: policy}
+}
+
+// Usage returns the accounting so far
+func (t *UsageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// seal checks the policy and counts a Seal of n bytes if it is allowed
+func (t *UsageTracker) seal(n int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.policy != nil {
+		if err := t.policy(t.usage, n); err != nil {
+			return err
+		}
+	}
+	t.usage.Seals++
+	t.usage.SealedBytes += uint64(n)
+	return nil
+}
+
+// open counts an Open of n bytes
+func (t *UsageTracker) open(n int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.Opens++
+	if !ok {
+		t.usage.OpenFailures++
+		return
+	}
+	t.usage.OpenedBytes += uint64(n)
+}
+
+// WithUsageTracker makes GCM count its use in t and consult the policy of t
+// before each Seal. Use one UsageTracker per key.
+func WithUsageTracker(t *UsageTracker) GCMOption {
+	return func(c *gcmConfig) { c.usage = t }
+}
@@ -0,0 +1,103 @@
+package toyaes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUsageTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewUsageTracker(nil)
+	aead := NewGCM(NewToyAES(make([]byte, 16)), WithUsageTracker(tracker))
+	nonce := make([]byte, 12)
+
+	s1 := aead.Seal(nil, nonce, make([]byte, 10), nil)
+	s2 := aead.Seal(nil, nonce, make([]byte, 100), nil)
+	if _, err := aead.Open(nil, nonce, s1, nil); err != nil {
+		t.Fatal(err)
+	}
+	s2[0] ^= 1
+	if _, err := aead.Open(nil, nonce, s2, nil); err == nil {
+		t.Fatal("modified ciphertext is accepted")
+	}
+	if _, err := aead.Open(nil, nonce, s1[:3], nil); err == nil {
+		t.Fatal("short ciphertext is accepted")
+	}
+
+	want := Usage{Seals: 2, SealedBytes: 110, Opens: 3, OpenFailures: 2, OpenedBytes: 10}
+	if got := tracker.Usage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Usage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSealLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		maxSeals uint64
+		maxBytes uint64
+		sizes    []int
+		// allowed is the number of Seal calls that succeed
+		allowed int
+	}{
+		{name: "seals", maxSeals: 3, sizes: []int{1, 1, 1, 1}, allowed: 3},
+		{name: "bytes", maxBytes: 100, sizes: []int{50, 50, 0, 1}, allowed: 3},
+		{name: "bytes in one message", maxBytes: 100, sizes: []int{101}, allowed: 0},
+		{name: "no limit", sizes: []int{1000, 1000, 1000}, allowed: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tracker := NewUsageTracker(SealLimit(tt.maxSeals, tt.maxBytes))
+			aead := NewGCM(NewToyAES(make([]byte, 16)), WithUsageTracker(tracker))
+			nonce := make([]byte, 12)
+
+			seal := func(n int) (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						err, _ = r.(error)
+					}
+				}()
+				aead.Seal(nil, nonce, make([]byte, n), nil)
+				return nil
+			}
+			for i, n := range tt.sizes {
+				err := seal(n)
+				if i < tt.allowed && err != nil {
+					t.Fatalf("Seal %d: %v", i, err)
+				}
+				if i >= tt.allowed && !errors.Is(err, ErrUsageLimit) {
+					t.Fatalf("Seal %d: err = %v, want ErrUsageLimit", i, err)
+				}
+			}
+			if got := tracker.Usage().Seals; got != uint64(tt.allowed) {
+				t.Errorf("Seals = %d, want %d", got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestUsagePolicy_RandomNonce(t *testing.T) {
+	t.Parallel()
+
+	var calls []Usage
+	tracker := NewUsageTracker(func(u Usage, n int) error {
+		calls = append(calls, u)
+		return SealLimit(RandomNonceMaxSeals, 0)(u, n)
+	})
+	aead, err := NewGCMWithRandomNonce(NewToyAES(make([]byte, 16)), WithUsageTracker(tracker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead.Seal(nil, nil, make([]byte, 5), nil)
+	aead.Seal(nil, nil, make([]byte, 7), nil)
+
+	want := []Usage{{}, {Seals: 1, SealedBytes: 5}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("policy is called with %+v, want %+v", calls, want)
+	}
+}
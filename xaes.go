@@ -0,0 +1,89 @@
+package toyaes
+
+import (
+	ccipher "crypto/cipher"
+)
+
+// xaesNonceSize is the nonce size of XAES-256-GCM
+const xaesNonceSize = 24
+
+var _ ccipher.AEAD = (*xaesGCM)(nil)
+
+// xaesGCM is XAES-256-GCM, which derives an AES-256-GCM key from the first
+// half of a 24-byte nonce
+type xaesGCM struct {
+	block ccipher.Block
+	// k1 is the subkey of CMAC for block
+	k1   [size]byte
+	opts []GCMOption
+}
+
+// NewXAES256GCM returns XAES-256-GCM (https://c2sp.org/XAES-256-GCM) for a
+// 32-byte key. It takes a 24-byte nonce, which is safe to choose at random
+// for practically any number of messages.
+//
+// The first 12 bytes of the nonce and the key derive an AES-256 key by
+// NIST SP 800-108r1 KDF in counter mode with CMAC-AES-256, and the last
+// 12 bytes are the nonce of AES-256-GCM with the derived key.
+func NewXAES256GCM(key []byte, opts ...GCMOption) (ccipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, KeySizeError(len(key))
+	}
+	c := &xaesGCM{block: NewToyAES(key), opts: opts}
+
+	// K1 of CMAC (SP 800-38B section 6.1): L = E(K, 0^128) doubled in GF(2^128)
+	c.block.Encrypt(c.k1[:], c.k1[:])
+	var msb byte
+	for i := len(c.k1) - 1; i >= 0; i-- {
+		msb, c.k1[i] = c.k1[i]>>7, c.k1[i]<<1|msb
+	}
+	c.k1[len(c.k1)-1] ^= msb * 0x87
+	return c, nil
+}
+
+// deriveKey returns the AES-256-GCM for nonce. The KDF input is
+// [i]16 || "X" || 0x00 || nonce[:12] for i = 1, 2, which fits in one block,
+// so CMAC is one encryption of the block XOR K1.
+func (c *xaesGCM) deriveKey(nonce []byte) *toyGCM {
+	var key [32]byte
+	m1, m2 := key[:size], key[size:]
+	m1[1], m1[2] = 1, 'X'
+	copy(m1[4:], nonce[:12])
+	copy(m2, m1)
+	m2[1] = 2
+	for i := range c.k1 {
+		m1[i] ^= c.k1[i]
+		m2[i] ^= c.k1[i]
+	}
+	c.block.Encrypt(m1, m1)
+	c.block.Encrypt(m2, m2)
+
+	g, err := newGCM(NewToyAES(key[:]), gcmStandardNonceSize, gcmTagSize, c.opts...)
+	if err != nil {
+		// the options are the same for every message, so this is a programming error
+		panic(err)
+	}
+	return g.(*toyGCM)
+}
+
+// NonceSize implements cipher.AEAD
+func (*xaesGCM) NonceSize() int { return xaesNonceSize }
+
+// Overhead implements cipher.AEAD
+func (*xaesGCM) Overhead() int { return gcmTagSize }
+
+// Seal implements cipher.AEAD
+func (c *xaesGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != xaesNonceSize {
+		panic("toyaes: incorrect nonce length given to XAES-256-GCM")
+	}
+	return c.deriveKey(nonce).Seal(dst, nonce[12:], plaintext, additionalData)
+}
+
+// Open implements cipher.AEAD
+func (c *xaesGCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != xaesNonceSize {
+		return nil, NonceSizeError(len(nonce))
+	}
+	return c.deriveKey(nonce).Open(dst, nonce[12:], ciphertext, additionalData)
+}
@@ -0,0 +1,111 @@
+package toyaes
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// https://github.com/C2SP/C2SP/blob/main/XAES-256-GCM.md#test-vectors
+func TestXAES256GCM(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		key            byte
+		plaintext      string
+		additionalData string
+		want           string
+	}{
+		{
+			name:      "without additional data",
+			key:       0x01,
+			plaintext: "XAES-256-GCM",
+			want:      "ce546ef63c9cc60765923609b33a9a1974e96e52daf2fcf7075e2271",
+		},
+		{
+			name:           "with additional data",
+			key:            0x03,
+			plaintext:      "XAES-256-GCM",
+			additionalData: "c2sp.org/XAES-256-GCM",
+			want:           "986ec1832593df5443a179437fd083bf3fdb41abd740a21f71eb769d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			aead, err := NewXAES256GCM(bytes.Repeat([]byte{tt.key}, 32))
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce := []byte("ABCDEFGHIJKLMNOPQRSTUVWX")
+			got := aead.Seal(nil, nonce, []byte(tt.plaintext), []byte(tt.additionalData))
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Seal = %x, want %s", got, tt.want)
+			}
+			p, err := aead.Open(nil, nonce, got, []byte(tt.additionalData))
+			if err != nil || string(p) != tt.plaintext {
+				t.Errorf("Open = %q, %v, want %q", p, err, tt.plaintext)
+			}
+		})
+	}
+}
+
+// xaesLongEnv enables the 1,000,000-iteration vector, which takes minutes
+const xaesLongEnv = "TOYAES_XAES_1M"
+
+func TestXAES256GCM_Accumulated(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{iterations: 10_000, want: "e6b9edf2df6cec60c8cbd864e2211b597fb69a529160cd040d56c0c210081939"},
+		{iterations: 1_000_000, want: "2163ae1445985a30b60585ee67daa55674df06901b890593e824b8a7c885ab15"},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.iterations), func(t *testing.T) {
+			t.Parallel()
+			if tt.iterations > 10_000 && os.Getenv(xaesLongEnv) == "" {
+				t.Skipf("set %s=1 to run", xaesLongEnv)
+			}
+
+			s, d := sha3.NewSHAKE128(), sha3.NewSHAKE128()
+			for i := 0; i < tt.iterations; i++ {
+				key := make([]byte, 32)
+				_, _ = s.Read(key)
+				nonce := make([]byte, 24)
+				_, _ = s.Read(nonce)
+				n := make([]byte, 1)
+				_, _ = s.Read(n)
+				plaintext := make([]byte, n[0])
+				_, _ = s.Read(plaintext)
+				_, _ = s.Read(n)
+				additionalData := make([]byte, n[0])
+				_, _ = s.Read(additionalData)
+
+				aead, err := NewXAES256GCM(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ciphertext := aead.Seal(nil, nonce, plaintext, additionalData)
+				_, _ = d.Write(ciphertext)
+
+				got, err := aead.Open(nil, nonce, ciphertext, additionalData)
+				if err != nil || !bytes.Equal(got, plaintext) {
+					t.Fatalf("Open = %X, %v, want %X", got, err, plaintext)
+				}
+			}
+			sum := make([]byte, 32)
+			_, _ = d.Read(sum)
+			if got := hex.EncodeToString(sum); got != tt.want {
+				t.Errorf("accumulated hash = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}